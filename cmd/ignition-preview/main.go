@@ -0,0 +1,221 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// ignition-preview renders the ignition of a Machine like the machine controller does, without a metal cluster
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	machinev1alpha1 "github.com/gardener/machine-controller-manager/pkg/apis/machine/v1alpha1"
+	"github.com/gardener/machine-controller-manager/pkg/util/provider/driver"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/cmd"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/ignition"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/metal"
+	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/component-base/cli/flag"
+	"k8s.io/component-base/logs"
+	capiv1beta1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	machineClassPath string
+	secretPath       string
+	machinePath      string
+	machineName      string
+	serverPath       string
+	addressesPath    string
+	objectsPath      string
+
+	options = metal.IgnitionPreviewOptions{
+		NodeNamePolicy: cmd.NodeNamePolicyServerClaimName,
+	}
+)
+
+func main() {
+	logs.AddFlags(pflag.CommandLine)
+	AddFlags(pflag.CommandLine)
+
+	flag.InitFlags()
+	logs.InitLogs()
+	defer logs.FlushLogs()
+
+	if err := run(context.Background(), os.Stdout); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
+func AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&machineClassPath, "machine-class", "", "Path to the MachineClass YAML.")
+	fs.StringVar(&secretPath, "secret", "", "Path to the YAML of the MachineClass Secret containing the user data.")
+	fs.StringVar(&machinePath, "machine", "", "Path to the Machine YAML. Defaults to a Machine named after --machine-name.")
+	fs.StringVar(&machineName, "machine-name", "machine-0", "Name of the Machine if no Machine YAML is given.")
+	fs.StringVar(&serverPath, "server", "", "Path to the YAML of the Server the Machine is bound to. Defaults to an empty Server.")
+	fs.StringVar(&addressesPath, "addresses", "", "Path to a YAML mapping the metadata keys of the IPAM configs to lists of IPAddress specs, the IPv6 address of a dual-stack network follows its IPv4 address.")
+	fs.StringVar(&objectsPath, "objects", "", "Path to a multi-document YAML of further objects in the metal namespace, e.g. ConfigMaps and Secrets referenced by the provider spec.")
+	fs.StringVar(&options.Namespace, "metal-namespace", "default", "Namespace of the metal cluster the objects are in.")
+	fs.Var(&options.NodeNamePolicy, "node-name-policy", fmt.Sprintf("Define the node name policy. Possible values are '%s', '%s' and '%s'.", cmd.NodeNamePolicyBMCName, cmd.NodeNamePolicyServerName, cmd.NodeNamePolicyServerClaimName))
+	fs.IntVar(&options.IgnitionSizeOptions.CompressionThreshold, "ignition-compression-threshold", 0, "Size(in bytes) of a rendered ignition above which its file contents are gzip compressed. Zero, the default, disables the compression.")
+	fs.IntVar(&options.IgnitionSizeOptions.MaxSize, "ignition-max-size", ignition.DefaultMaxSize, "Maximum size(in bytes) of an ignition Secret. Larger ignitions are split into a pointer config and parts stored in separate Secrets. Zero disables the splitting.")
+	fs.StringVar(&options.IgnitionSizeOptions.PartBaseURL, "ignition-part-base-url", "", "URL the parts of split ignitions are served from as <url>/<namespace>/<secret-name>, e.g. the URL the ignition part server of the machine-controller is reachable at. Ignitions exceeding the maximum size fail to render if it is not set.")
+}
+
+func run(ctx context.Context, w io.Writer) error {
+	if machineClassPath == "" || secretPath == "" {
+		return errors.New("--machine-class and --secret are required")
+	}
+
+	req := &driver.InitializeMachineRequest{
+		MachineClass: &machinev1alpha1.MachineClass{},
+		Machine:      &machinev1alpha1.Machine{},
+		Secret:       &corev1.Secret{},
+	}
+	if err := readYAML(machineClassPath, req.MachineClass); err != nil {
+		return err
+	}
+	if err := readYAML(secretPath, req.Secret); err != nil {
+		return err
+	}
+	addStringData(req.Secret)
+	if machinePath != "" {
+		if err := readYAML(machinePath, req.Machine); err != nil {
+			return err
+		}
+	} else {
+		req.Machine.Name = machineName
+	}
+
+	if serverPath != "" {
+		options.Server = &metalv1alpha1.Server{}
+		if err := readYAML(serverPath, options.Server); err != nil {
+			return err
+		}
+	}
+	if addressesPath != "" {
+		options.Addresses = map[string][]capiv1beta1.IPAddressSpec{}
+		if err := readYAML(addressesPath, &options.Addresses); err != nil {
+			return err
+		}
+	}
+	if objectsPath != "" {
+		objects, err := readObjects(objectsPath, options.Namespace)
+		if err != nil {
+			return err
+		}
+		options.Objects = objects
+	}
+
+	preview, err := metal.PreviewIgnition(ctx, req, options)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "# Butane config\n%s\n", preview.Butane); err != nil {
+		return err
+	}
+	for _, secret := range append([]*corev1.Secret{preview.Secret}, preview.Parts...) {
+		if err := writeIgnitionSecret(w, secret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeIgnitionSecret writes the ignition of a Secret as indented JSON
+func writeIgnitionSecret(w io.Writer, secret *corev1.Secret) error {
+	keys := make([]string, 0, len(secret.Data))
+	for key := range secret.Data {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		content := secret.Data[key]
+		indented := &bytes.Buffer{}
+		if err := json.Indent(indented, content, "", "  "); err == nil {
+			content = indented.Bytes()
+		}
+		if _, err := fmt.Fprintf(w, "# Ignition config in key %q of Secret %q\n%s\n", key, client.ObjectKeyFromObject(secret), content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readYAML(path string, obj any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(data, obj); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return nil
+}
+
+// readObjects reads the objects of a multi-document YAML, objects without namespace are put into the given one
+func readObjects(path, namespace string) ([]client.Object, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var objects []client.Object
+	decoder := utilyaml.NewYAMLOrJSONDecoder(file, 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(obj); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+			return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(namespace)
+		}
+
+		// the string data of Secrets is only merged into their data by the API server
+		if obj.GetKind() == "Secret" {
+			secret := &corev1.Secret{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, secret); err != nil {
+				return nil, fmt.Errorf("failed to decode Secret %q: %w", client.ObjectKeyFromObject(obj), err)
+			}
+			addStringData(secret)
+			objects = append(objects, secret)
+			continue
+		}
+		objects = append(objects, obj)
+	}
+}
+
+func addStringData(secret *corev1.Secret) {
+	if len(secret.StringData) == 0 {
+		return
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	for key, value := range secret.StringData {
+		secret.Data[key] = []byte(value)
+	}
+	secret.StringData = nil
+}
@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/cmd"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/metal"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/pflag"
+)
+
+const (
+	machineClassYAML = `apiVersion: machine.sapcloud.io/v1alpha1
+kind: MachineClass
+metadata:
+  name: machine-class
+provider: ironcore-metal
+providerSpec:
+  image: my-image
+  serverLabels:
+    instance-type: bar
+  metadata:
+    foo: bar
+  ipamConfig:
+    - metadataKey: pool-a
+      ipamRef:
+        apiGroup: ipam.cluster.x-k8s.io
+        kind: GlobalInClusterIPPool
+        name: pool-a
+  ignitionRefs:
+    - configMapRef:
+        name: extra
+        key: ignition
+`
+	secretYAML = `apiVersion: v1
+kind: Secret
+metadata:
+  name: machine-class
+stringData:
+  userData: |
+    #!/bin/bash
+    echo hello
+`
+	serverYAML = `apiVersion: metal.ironcore.dev/v1alpha1
+kind: Server
+metadata:
+  name: server-0
+spec:
+  systemUUID: "12345"
+status:
+  networkInterfaces:
+    - name: eth0
+      macAddress: aa:bb:cc:dd:ee:ff
+`
+	addressesYAML = `pool-a:
+  - address: 10.0.0.10
+    prefix: 24
+    gateway: 10.0.0.1
+`
+	objectsYAML = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: extra
+data:
+  ignition: |
+    storage:
+      files:
+        - path: /etc/extra
+          contents:
+            inline: extra
+`
+)
+
+var _ = Describe("ignition-preview", func() {
+	var dir string
+
+	writeFile := func(name, content string) string {
+		GinkgoHelper()
+		path := filepath.Join(dir, name)
+		Expect(os.WriteFile(path, []byte(content), 0600)).To(Succeed())
+		return path
+	}
+
+	parseFlags := func(args ...string) {
+		GinkgoHelper()
+		options = metal.IgnitionPreviewOptions{NodeNamePolicy: cmd.NodeNamePolicyServerClaimName}
+		fs := pflag.NewFlagSet("ignition-preview", pflag.ContinueOnError)
+		AddFlags(fs)
+		Expect(fs.Parse(args)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	It("should print the Butane config and the ignition of a Machine", func(ctx SpecContext) {
+		parseFlags(
+			"--machine-class", writeFile("machine-class.yaml", machineClassYAML),
+			"--secret", writeFile("secret.yaml", secretYAML),
+			"--server", writeFile("server.yaml", serverYAML),
+			"--addresses", writeFile("addresses.yaml", addressesYAML),
+			"--objects", writeFile("objects.yaml", objectsYAML),
+		)
+
+		out := &bytes.Buffer{}
+		Expect(run(ctx, out)).To(Succeed())
+
+		butane, ignitionJSON, ok := strings.Cut(out.String(), "# Ignition config in key \"ignition\" of Secret \"default/machine-0\"\n")
+		Expect(ok).To(BeTrue(), "missing ignition in output:\n%s", out.String())
+		Expect(butane).To(SatisfyAll(
+			HavePrefix("# Butane config\n"),
+			ContainSubstring("machine-0"),
+			ContainSubstring("10.0.0.10"),
+		))
+
+		ignition := map[string]any{}
+		Expect(json.Unmarshal([]byte(ignitionJSON), &ignition)).To(Succeed())
+		var paths []string
+		for _, file := range ignition["storage"].(map[string]any)["files"].([]any) {
+			paths = append(paths, file.(map[string]any)["path"].(string))
+		}
+		Expect(paths).To(ContainElements(
+			"/etc/hostname",
+			"/etc/extra",
+			"/var/lib/metal-cloud-config/metadata",
+			"/etc/NetworkManager/system-connections/eth0.nmconnection",
+		))
+	})
+
+	It("should fail if an IPAddressClaim has no address", func(ctx SpecContext) {
+		parseFlags(
+			"--machine-class", writeFile("machine-class.yaml", machineClassYAML),
+			"--secret", writeFile("secret.yaml", secretYAML),
+			"--objects", writeFile("objects.yaml", objectsYAML),
+		)
+
+		Expect(run(ctx, &bytes.Buffer{})).To(MatchError(ContainSubstring("not bound")))
+	})
+
+	It("should require the MachineClass and its Secret", func(ctx SpecContext) {
+		parseFlags("--machine-class", writeFile("machine-class.yaml", machineClassYAML))
+
+		Expect(run(ctx, &bytes.Buffer{})).To(MatchError("--machine-class and --secret are required"))
+	})
+})
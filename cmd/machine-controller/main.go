@@ -4,27 +4,61 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/cmd"
 
+	machinev1alpha1 "github.com/gardener/machine-controller-manager/pkg/apis/machine/v1alpha1"
 	_ "github.com/gardener/machine-controller-manager/pkg/util/client/metrics/prometheus" // for client metric registration
 	"github.com/gardener/machine-controller-manager/pkg/util/provider/app"
 	mcmoptions "github.com/gardener/machine-controller-manager/pkg/util/provider/app/options"
 	_ "github.com/gardener/machine-controller-manager/pkg/util/reflector/prometheus" // for reflector metric registration
 	_ "github.com/gardener/machine-controller-manager/pkg/util/workqueue/prometheus" // for workqueue metric registration
 	mcmclient "github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/client"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/ignition"
 	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/metal"
 	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/component-base/cli/flag"
 	"k8s.io/component-base/logs"
+	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
 
+// orphanCollectorLeaseName is the name of the lease in the control namespace the orphan collector is run by the leader of
+const orphanCollectorLeaseName = "machine-controller-orphan-collector"
+
 var (
 	KubeconfigPath string
 	nodeNamePolicy cmd.NodeNamePolicy = cmd.NodeNamePolicyServerClaimName
+	csiDriverNames []string
+	enableCache    bool
+
+	serverBindingTimeout time.Duration
+
+	ignitionSizeOptions           metal.IgnitionSizeOptions
+	ignitionPartServerBindAddress string
+
+	orphanCollectorOptions metal.OrphanCollectorOptions
+
+	webhookOptions webhook.Options
 )
 
 func main() {
@@ -38,13 +72,45 @@ func main() {
 	logs.InitLogs()
 	defer logs.FlushLogs()
 
-	clientProvider, namespace, err := mcmclient.NewProviderAndNamespace(ctrl.SetupSignalHandler(), KubeconfigPath)
+	ctx := ctrl.SetupSignalHandler()
+	clientProvider, namespace, err := mcmclient.NewProviderAndNamespace(ctx, KubeconfigPath, enableCache)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	machineRecorder, err := newMachineEventRecorder(ctx, s)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
 
-	drv := metal.NewDriver(clientProvider, namespace, nodeNamePolicy)
+	drv := metal.NewDriver(clientProvider, namespace, metal.DriverOptions{
+		NodeNamePolicy:       nodeNamePolicy,
+		CSIDriverNames:       csiDriverNames,
+		ServerBindingTimeout: serverBindingTimeout,
+		IgnitionSizeOptions:  ignitionSizeOptions,
+		Recorder:             clientProvider.NewEventRecorder(ctx),
+		MachineRecorder:      machineRecorder,
+	})
+
+	if orphanCollectorOptions.Interval > 0 {
+		if err := startOrphanCollector(ctx, s, clientProvider, namespace); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if ignitionPartServerBindAddress != "" {
+		startIgnitionPartServer(ctx, clientProvider, namespace)
+	}
+
+	if webhookOptions.Port > 0 {
+		if err := startWebhookServer(ctx, s, clientProvider, namespace); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
 
 	if err := app.Run(s, drv); err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "%v\n", err)
@@ -55,4 +121,153 @@ func main() {
 func AddExtraFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&KubeconfigPath, "metal-kubeconfig", "", "Path to the metal cluster kubeconfig.")
 	fs.Var(&nodeNamePolicy, "node-name-policy", fmt.Sprintf("Define the node name policy. Possible values are '%s', '%s' and '%s'.", cmd.NodeNamePolicyBMCName, cmd.NodeNamePolicyServerName, cmd.NodeNamePolicyServerClaimName))
+	fs.StringSliceVar(&csiDriverNames, "csi-driver-names", nil, "Additional CSI driver names whose volumes are reported by GetVolumeIDs.")
+	fs.DurationVar(&serverBindingTimeout, "server-binding-timeout", 0, "Time after the creation of a ServerClaim after which it is released if no Server was bound. Zero disables the timeout.")
+	fs.IntVar(&ignitionSizeOptions.CompressionThreshold, "ignition-compression-threshold", 0, "Size(in bytes) of a rendered ignition above which its file contents are gzip compressed. Zero, the default, disables the compression.")
+	fs.IntVar(&ignitionSizeOptions.MaxSize, "ignition-max-size", ignition.DefaultMaxSize, "Maximum size(in bytes) of an ignition Secret. Larger ignitions are split into a pointer config and parts stored in separate Secrets. Zero disables the splitting.")
+	fs.StringVar(&ignitionSizeOptions.PartBaseURL, "ignition-part-base-url", "", "URL the parts of split ignitions are served from as <url>/<namespace>/<secret-name>, e.g. the URL the --ignition-part-server-bind-address is reachable at from the Servers. Ignitions exceeding the maximum size fail to render if it is not set.")
+	fs.StringVar(&ignitionPartServerBindAddress, "ignition-part-server-bind-address", "", "Address the parts of split ignitions are served on as /<namespace>/<secret-name>. Empty, the default, disables the server.")
+	fs.DurationVar(&orphanCollectorOptions.Interval, "orphan-collector-interval", 0, "Interval in which ServerClaims without Machine and ignition Secrets and IPAddressClaims without ServerClaim are collected from the metal namespace. With leader election, only the leader of the machine-controller-orphan-collector lease runs the collector. Zero disables the collector.")
+	fs.DurationVar(&orphanCollectorOptions.GracePeriod, "orphan-collector-grace-period", time.Hour, "Time a ServerClaim has to be without Machine, or an ignition Secret or IPAddressClaim without ServerClaim, before it is deleted by the orphan collector.")
+	fs.BoolVar(&orphanCollectorOptions.DryRun, "orphan-collector-dry-run", false, "Only log the orphaned objects found by the orphan collector instead of deleting them.")
+	fs.IntVar(&webhookOptions.Port, "webhook-port", 0, "Port the MachineClass validation webhook is served on. Zero disables the webhook.")
+	fs.StringVar(&webhookOptions.CertDir, "webhook-cert-dir", "", "Directory containing the tls.crt and tls.key of the webhook server.")
+	fs.BoolVar(&enableCache, "metal-cache", false, "Serve reads of ServerClaims, Servers and IPAddressClaims from an informer cache of the metal namespace.")
+}
+
+// getControlConfig returns the config of the control cluster, which defaults to the target cluster like in
+// machine-controller-manager
+func getControlConfig(s *mcmoptions.MCServer) (*rest.Config, error) {
+	kubeconfig := s.ControlKubeconfig
+	switch kubeconfig {
+	case "":
+		kubeconfig = s.TargetKubeconfig
+	case "inClusterConfig":
+		kubeconfig = ""
+	}
+	controlConfig, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get control cluster config: %w", err)
+	}
+	return controlConfig, nil
+}
+
+// newMachineEventRecorder returns an event recorder which emits the lifecycle events on the Machines in the control
+// cluster until ctx is done
+func newMachineEventRecorder(ctx context.Context, s *mcmoptions.MCServer) (record.EventRecorder, error) {
+	controlConfig, err := getControlConfig(s)
+	if err != nil {
+		return nil, err
+	}
+	eventClient, err := kubernetes.NewForConfig(rest.AddUserAgent(controlConfig, "machine-events"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create control cluster event client: %w", err)
+	}
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(machinev1alpha1.AddToScheme(scheme))
+	broadcaster := record.NewBroadcaster(record.WithContext(ctx))
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: eventClient.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme, corev1.EventSource{Component: mcmclient.EventSource}), nil
+}
+
+// startOrphanCollector starts the orphan collector, which reads the Machines from the control cluster. If leader
+// election is enabled, only the leader of its own lease in the control namespace runs the collector.
+func startOrphanCollector(ctx context.Context, s *mcmoptions.MCServer, clientProvider *mcmclient.Provider, namespace string) error {
+	controlConfig, err := getControlConfig(s)
+	if err != nil {
+		return err
+	}
+	controlClient, err := client.New(controlConfig, client.Options{})
+	if err != nil {
+		return fmt.Errorf("failed to create control cluster client: %w", err)
+	}
+	collector := metal.NewOrphanCollector(clientProvider, namespace, controlClient, s.Namespace, orphanCollectorOptions)
+
+	if !s.LeaderElection.LeaderElect {
+		go collector.Start(ctx)
+		return nil
+	}
+
+	id, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("failed to get leader election identity: %w", err)
+	}
+	leaderElectionClient, err := kubernetes.NewForConfig(rest.AddUserAgent(controlConfig, "orphan-collector-leader-election"))
+	if err != nil {
+		return fmt.Errorf("failed to create leader election client: %w", err)
+	}
+	lock, err := resourcelock.New(s.LeaderElection.ResourceLock, s.Namespace, orphanCollectorLeaseName, leaderElectionClient.CoreV1(), leaderElectionClient.CoordinationV1(), resourcelock.ResourceLockConfig{
+		Identity: id,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create leader election lock: %w", err)
+	}
+
+	// the election is run again after the leadership has been lost, until the context is done
+	go wait.UntilWithContext(ctx, func(ctx context.Context) {
+		leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+			Lock:            lock,
+			LeaseDuration:   s.LeaderElection.LeaseDuration.Duration,
+			RenewDeadline:   s.LeaderElection.RenewDeadline.Duration,
+			RetryPeriod:     s.LeaderElection.RetryPeriod.Duration,
+			ReleaseOnCancel: true,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: collector.Start,
+				OnStoppedLeading: func() {
+					klog.InfoS("Stopped leading the orphan collector", "namespace", s.Namespace, "lease", orphanCollectorLeaseName)
+				},
+			},
+		})
+	}, s.LeaderElection.RetryPeriod.Duration)
+	return nil
+}
+
+// startWebhookServer starts the server of the MachineClass validation webhook, which reads the Secrets of the
+// MachineClasses from the control cluster
+func startWebhookServer(ctx context.Context, s *mcmoptions.MCServer, clientProvider *mcmclient.Provider, namespace string) error {
+	controlConfig, err := getControlConfig(s)
+	if err != nil {
+		return err
+	}
+	controlClient, err := client.New(controlConfig, client.Options{})
+	if err != nil {
+		return fmt.Errorf("failed to create control cluster client: %w", err)
+	}
+
+	server := webhook.NewServer(webhookOptions)
+	server.Register(metal.MachineClassValidationPath, &webhook.Admission{
+		Handler: metal.NewMachineClassValidator(clientProvider, namespace, controlClient),
+	})
+	go func() {
+		if err := server.Start(ctx); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "failed to run webhook server: %v\n", err)
+			os.Exit(1)
+		}
+	}()
+	return nil
+}
+
+// startIgnitionPartServer starts the server of the parts of split ignitions, which is shut down when ctx is done
+func startIgnitionPartServer(ctx context.Context, clientProvider *mcmclient.Provider, namespace string) {
+	server := &http.Server{
+		Addr:              ignitionPartServerBindAddress,
+		Handler:           metal.NewIgnitionPartHandler(clientProvider, namespace),
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			klog.ErrorS(err, "Failed to shut down ignition part server")
+		}
+	}()
+	go func() {
+		klog.InfoS("Serving ignition parts", "address", ignitionPartServerBindAddress)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			_, _ = fmt.Fprintf(os.Stderr, "failed to run ignition part server: %v\n", err)
+			os.Exit(1)
+		}
+	}()
 }
@@ -5,6 +5,9 @@ package v1alpha1
 
 import (
 	"net/netip"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
@@ -12,32 +15,97 @@ const (
 	V1Alpha1 = "mcm.gardener.cloud/v1alpha1"
 	// ProviderName is the provider name
 	ProviderName = "ironcore-metal"
-	// LoopbackAddressAnnotation is the annotation used to specify a loopback address for the Machine
+	// LoopbackAddressAnnotation is the annotation used to specify the loopback addresses for the Machine,
+	// an IPv4 and an IPv6 address of dual-stack Machines are separated by a comma. Entries which are no IP addresses
+	// are skipped with a warning event.
 	LoopbackAddressAnnotation = "metal.ironcore.dev/loopback-address"
+	// ServerNameAnnotation is the annotation used to pin a Machine to the Server with the given name
+	ServerNameAnnotation = "metal.ironcore.dev/server-name"
+
+	// TemplateNameFCOS is the name of the embedded template for Fedora CoreOS
+	TemplateNameFCOS = "fcos"
+	// TemplateNameFlatcar is the name of the embedded template for Flatcar
+	TemplateNameFlatcar = "flatcar"
 )
 
+// NetworkFormat is the format of the network configuration files of the operating system of a Machine
+type NetworkFormat string
+
+const (
+	// NetworkFormatNetworkd is the format of systemd-networkd .network files
+	NetworkFormatNetworkd NetworkFormat = "networkd"
+	// NetworkFormatNetworkManager is the format of NetworkManager keyfiles
+	NetworkFormatNetworkManager NetworkFormat = "networkmanager"
+)
+
+// TemplateNames are the names of the OS templates embedded in the driver
+var TemplateNames = []string{TemplateNameFCOS, TemplateNameFlatcar}
+
 // ProviderSpec is the spec to be used while parsing the calls
 type ProviderSpec struct {
 	// Image is the URL pointing to an OCI registry containing the operating system image which should be used to boot the Machine
 	Image string `json:"image,omitempty"`
 	// Ignition contains the ignition configuration which should be run on first boot of a Machine.
+	// It is merged with our template like Ignition merges configs, e.g. files with the same path or units with the
+	// same name are merged into one entry. The merge_strategy field of an entry, one of merge, replace or remove,
+	// overrides how it is merged with the entry of the template. Files generated from DnsServers, Metadata and
+	// IPAMConfig replace the files with the same path.
 	Ignition string `json:"ignition,omitempty"`
 	// By default, if ignition is set it will be merged it with our template
-	// If IgnitionOverride is set to true allows to fully override
+	// If IgnitionOverride is set to true, entries of the template are replaced instead of merged by default
 	IgnitionOverride bool `json:"ignitionOverride,omitempty"`
 	// IgnitionSecretKey is optional key field used to identify the ignition content in the Secret
-	// If the key is empty, the DefaultIgnitionKey will be used as fallback.
+	// If the key is empty, the default key "ignition" will be used as fallback. Existing ignition Secrets are
+	// migrated to the new key when it is changed.
 	IgnitionSecretKey string `json:"ignitionSecretKey,omitempty"`
+	// IgnitionRefs reference ignition configurations in Secrets and ConfigMaps, e.g. to keep credentials out of the
+	// MachineClass. They are merged in the given order after Ignition and the user data in the same way as Ignition.
+	IgnitionRefs []IgnitionReference `json:"ignitionRefs,omitempty"`
 	// Labels are used to tag resources which the MCM creates, so they can be identified later.
 	Labels map[string]string `json:"labels,omitempty"`
 	// DnsServers is a list of DNS resolvers which should be configured on the host.
 	DnsServers []netip.Addr `json:"dnsServers,omitempty"`
 	// ServerLabels are passed to the ServerClaim to find a server with certain properties
 	ServerLabels map[string]string `json:"serverLabels,omitempty"`
+	// ServerMatchExpressions are passed to the ServerClaim in addition to the ServerLabels to find a server with certain properties.
+	// The supported operators are In, NotIn, Exists and DoesNotExist.
+	ServerMatchExpressions []metav1.LabelSelectorRequirement `json:"serverMatchExpressions,omitempty"`
+	// ServerNames maps Machine names to the names of the Servers they are pinned to.
+	// A pinned Machine is bound to the named Server instead of a Server matching the ServerLabels and ServerMatchExpressions.
+	// The ServerNameAnnotation on a Machine takes precedence over this mapping.
+	ServerNames map[string]string `json:"serverNames,omitempty"`
 	// Metadata is a key-value map of additional data which should be passed to the Machine.
 	Metadata map[string]any `json:"metadata,omitempty"`
 	// IPAMConfig is a list of references to Network resources that should be used to assign IP addresses to the worker nodes.
 	IPAMConfig []IPAMConfig `json:"ipamConfig,omitempty"`
+	// TemplateRef references the OS template the ignition of the Machine is based on.
+	// If not set, the default template for Fedora CoreOS is used.
+	TemplateRef *TemplateReference `json:"templateRef,omitempty"`
+	// NetworkFormat is the format of the network configuration files of the IPAMConfig, i.e. networkd or
+	// networkmanager. If not set, the format of the OS template is used. It has to be set if a custom template
+	// neither declares the format with its networkFormat key nor has a Butane variant of a known operating system.
+	NetworkFormat NetworkFormat `json:"networkFormat,omitempty"`
+}
+
+// TemplateReference is a reference to an OS template. Exactly one of Name and ConfigMapRef must be set.
+type TemplateReference struct {
+	// Name is the name of a template embedded in the driver, i.e. fcos or flatcar.
+	Name string `json:"name,omitempty"`
+	// ConfigMapRef references the key of a ConfigMap in the metal namespace which contains the template.
+	// The template may declare the network format of its operating system with the networkFormat key, e.g. for
+	// Garden Linux using systemd-networkd with the fcos variant.
+	ConfigMapRef *corev1.ConfigMapKeySelector `json:"configMapRef,omitempty"`
+}
+
+// IgnitionReference is a reference to a key containing an ignition configuration. Exactly one of
+// ProviderSecretKey, SecretRef and ConfigMapRef must be set.
+type IgnitionReference struct {
+	// ProviderSecretKey is a key of the provider Secret of the MachineClass.
+	ProviderSecretKey string `json:"providerSecretKey,omitempty"`
+	// SecretRef references a key of a Secret in the metal namespace.
+	SecretRef *corev1.SecretKeySelector `json:"secretRef,omitempty"`
+	// ConfigMapRef references a key of a ConfigMap in the metal namespace.
+	ConfigMapRef *corev1.ConfigMapKeySelector `json:"configMapRef,omitempty"`
 }
 
 // IPAMObjectReference is a reference to the IPAM object, which will be used for IP allocation.
@@ -56,4 +124,21 @@ type IPAMConfig struct {
 	MetadataKey string `json:"metadataKey"`
 	// IPAMRef is a reference to the IPAM object, which will be used for IP allocation.
 	IPAMRef *IPAMObjectReference `json:"ipamRef"`
+	// IPv6IPAMRef is a reference to the IPAM object, which will be used for the allocation of an IPv6 address of
+	// a dual-stack network. If set, IPAMRef has to allocate an IPv4 address.
+	IPv6IPAMRef *IPAMObjectReference `json:"ipv6IpamRef,omitempty"`
+	// Interface is the name of the network interface of the Server, as reported in its status, which is configured
+	// with the allocated IP address. The interface is matched by its MAC address on the Machine.
+	// If not set, the only network interface of the Server is configured, or the only one with an IP address in the
+	// subnet of the allocated address, as discovered during the inventory of the Server. Otherwise the address is not
+	// configured and a warning event is emitted.
+	Interface string `json:"interface,omitempty"`
+}
+
+// ServerSelector returns the label selector which is used to find a server for the Machine
+func (s *ProviderSpec) ServerSelector() *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchLabels:      s.ServerLabels,
+		MatchExpressions: s.ServerMatchExpressions,
+	}
 }
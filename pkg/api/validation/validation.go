@@ -6,11 +6,14 @@ package validation
 import (
 	"fmt"
 	"net/netip"
+	"slices"
 
 	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/v1alpha1"
 
 	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	utilvalidation "k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	capiv1beta1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
 )
@@ -18,8 +21,19 @@ import (
 const (
 	LabelKeyServerClaimName      = "metal.ironcore.dev/server-claim-name"
 	LabelKeyServerClaimNamespace = "metal.ironcore.dev/server-claim-namespace"
+	LabelKeyIgnitionSecretName   = "metal.ironcore.dev/ignition-secret-name"
+	// LabelKeyManagedBy is set to LabelValueManagedBy on the ServerClaims, IPAddressClaims and ignition Secrets
+	// created by the driver, so that orphaned objects can be found
+	LabelKeyManagedBy   = "app.kubernetes.io/managed-by"
+	LabelValueManagedBy = "machine-controller-manager-provider-ironcore-metal"
 
 	AnnotationKeyMCMMachineRecreate = "metal.ironcore.dev/mcm-machine-recreate"
+	// AnnotationKeyIgnoreUnknownFields on a MachineClass set to "true" lets unknown fields of its provider spec be
+	// ignored instead of rejected, e.g. for provider specs written for a newer version of the driver
+	AnnotationKeyIgnoreUnknownFields = "metal.ironcore.dev/ignore-unknown-provider-spec-fields"
+	// AnnotationKeyIgnitionSecretKey on an ignition Secret records the key its ignition is stored under, so that the
+	// ignition can be moved if the IgnitionSecretKey of the provider spec changes
+	AnnotationKeyIgnitionSecretKey = "metal.ironcore.dev/ignition-secret-key"
 )
 
 // ValidateProviderSpecAndSecret validates the provider spec and provider secret
@@ -29,9 +43,22 @@ func ValidateProviderSpecAndSecret(spec *v1alpha1.ProviderSpec, secret *corev1.S
 	allErrs = validateMachineClassSpec(spec, field.NewPath("spec"))
 	allErrs = append(allErrs, validateSecret(secret, field.NewPath("spec"))...)
 
+	if secret != nil {
+		for i, ignitionRef := range spec.IgnitionRefs {
+			if _, ok := secret.Data[ignitionRef.ProviderSecretKey]; ignitionRef.ProviderSecretKey != "" && !ok {
+				allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "ignitionRefs").Index(i).Child("providerSecretKey"), ignitionRef.ProviderSecretKey, "key is not set in the provider Secret"))
+			}
+		}
+	}
+
 	return allErrs
 }
 
+// ValidateProviderSpec validates the provider spec without the provider secret
+func ValidateProviderSpec(spec *v1alpha1.ProviderSpec, fldPath *field.Path) field.ErrorList {
+	return validateMachineClassSpec(spec, field.NewPath("spec"))
+}
+
 // validateSecret checks if the secret contains the required userData key
 func validateSecret(secret *corev1.Secret, fldPath *field.Path) field.ErrorList {
 	var allErrs field.ErrorList
@@ -48,7 +75,9 @@ func validateSecret(secret *corev1.Secret, fldPath *field.Path) field.ErrorList
 	return allErrs
 }
 
-// validateMachineClassSpec validates if image is set and if DNS servers are valid IP addresses
+// validateMachineClassSpec validates if image is set, if the ignition Secret key is a valid Secret data key, if DNS
+// servers are valid IP addresses and if the server selector, the pinned server names and the IPAM configs are
+// well-formed
 func validateMachineClassSpec(spec *v1alpha1.ProviderSpec, fldPath *field.Path) field.ErrorList {
 	var allErrs field.ErrorList
 
@@ -56,12 +85,159 @@ func validateMachineClassSpec(spec *v1alpha1.ProviderSpec, fldPath *field.Path)
 		allErrs = append(allErrs, field.Required(fldPath.Child("image"), "image is required"))
 	}
 
+	if spec.IgnitionSecretKey != "" {
+		// Secret data keys follow the same rules as ConfigMap keys
+		for _, msg := range utilvalidation.IsConfigMapKey(spec.IgnitionSecretKey) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("ignitionSecretKey"), spec.IgnitionSecretKey, msg))
+		}
+	}
+
 	for i, ip := range spec.DnsServers {
 		if !netip.Addr.IsValid(ip) {
 			allErrs = append(allErrs, field.Invalid(fldPath.Child("dnsServers").Index(i), ip, "ip is invalid"))
 		}
 	}
 
+	allErrs = append(allErrs, validateServerSelector(spec, fldPath)...)
+
+	for machineName, serverName := range spec.ServerNames {
+		for _, msg := range utilvalidation.IsDNS1123Subdomain(serverName) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("serverNames").Key(machineName), serverName, msg))
+		}
+	}
+
+	for i, ipamConfig := range spec.IPAMConfig {
+		allErrs = append(allErrs, validateIPAMConfig(ipamConfig, fldPath.Child("ipamConfig").Index(i))...)
+	}
+	allErrs = append(allErrs, validateIPv6ClaimNames(spec.IPAMConfig, fldPath.Child("ipamConfig"))...)
+
+	if spec.TemplateRef != nil {
+		allErrs = append(allErrs, validateTemplateRef(spec.TemplateRef, fldPath.Child("templateRef"))...)
+	}
+
+	for i, ignitionRef := range spec.IgnitionRefs {
+		allErrs = append(allErrs, validateIgnitionRef(ignitionRef, fldPath.Child("ignitionRefs").Index(i))...)
+	}
+
+	switch spec.NetworkFormat {
+	case "", v1alpha1.NetworkFormatNetworkd, v1alpha1.NetworkFormatNetworkManager:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("networkFormat"), spec.NetworkFormat, []v1alpha1.NetworkFormat{v1alpha1.NetworkFormatNetworkd, v1alpha1.NetworkFormatNetworkManager}))
+	}
+
+	return allErrs
+}
+
+// validateIPv6ClaimNames checks that the metadata keys of the IPAM configs do not collide with the suffixed metadata
+// keys the IPAddressClaims of IPv6 addresses of dual-stack networks are named after
+func validateIPv6ClaimNames(ipamConfigs []v1alpha1.IPAMConfig, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	ipv6MetadataKeys := make(map[string]string, len(ipamConfigs))
+	for _, ipamConfig := range ipamConfigs {
+		if ipamConfig.IPv6IPAMRef != nil {
+			ipv6MetadataKeys[ipamConfig.MetadataKey+"-ipv6"] = ipamConfig.MetadataKey
+		}
+	}
+
+	for i, ipamConfig := range ipamConfigs {
+		if metadataKey, ok := ipv6MetadataKeys[ipamConfig.MetadataKey]; ok {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i).Child("metadataKey"), ipamConfig.MetadataKey, fmt.Sprintf("metadata key collides with the IPAddressClaim of the IPv6 address of metadata key %q", metadataKey)))
+		}
+	}
+
+	return allErrs
+}
+
+// validateIPAMConfig checks if the IPv6 IPAM reference of a dual-stack IPAM config is complete
+func validateIPAMConfig(ipamConfig v1alpha1.IPAMConfig, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if ipamConfig.IPv6IPAMRef != nil {
+		if ipamConfig.IPv6IPAMRef.Name == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("ipv6IpamRef", "name"), "name is required"))
+		}
+		if ipamConfig.IPv6IPAMRef.Kind == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("ipv6IpamRef", "kind"), "kind is required"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateIgnitionRef checks if the ignition reference points either to a key of the provider Secret, of a Secret or
+// of a ConfigMap
+func validateIgnitionRef(ignitionRef v1alpha1.IgnitionReference, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	count := 0
+	if ignitionRef.ProviderSecretKey != "" {
+		count++
+	}
+	if ignitionRef.SecretRef != nil {
+		count++
+		if ignitionRef.SecretRef.Name == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("secretRef", "name"), "Secret name is required"))
+		}
+		if ignitionRef.SecretRef.Key == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("secretRef", "key"), "Secret key is required"))
+		}
+	}
+	if ignitionRef.ConfigMapRef != nil {
+		count++
+		if ignitionRef.ConfigMapRef.Name == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("configMapRef", "name"), "ConfigMap name is required"))
+		}
+		if ignitionRef.ConfigMapRef.Key == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("configMapRef", "key"), "ConfigMap key is required"))
+		}
+	}
+
+	switch count {
+	case 0:
+		allErrs = append(allErrs, field.Required(fldPath, "one of providerSecretKey, secretRef or configMapRef is required"))
+	case 1:
+	default:
+		allErrs = append(allErrs, field.Invalid(fldPath, ignitionRef, "providerSecretKey, secretRef and configMapRef are mutually exclusive"))
+	}
+
+	return allErrs
+}
+
+// validateTemplateRef checks if the template reference points either to an embedded template or to a ConfigMap key
+func validateTemplateRef(templateRef *v1alpha1.TemplateReference, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	switch {
+	case templateRef.Name != "" && templateRef.ConfigMapRef != nil:
+		allErrs = append(allErrs, field.Invalid(fldPath, templateRef, "name and configMapRef are mutually exclusive"))
+	case templateRef.Name != "":
+		if !slices.Contains(v1alpha1.TemplateNames, templateRef.Name) {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("name"), templateRef.Name, v1alpha1.TemplateNames))
+		}
+	case templateRef.ConfigMapRef != nil:
+		if templateRef.ConfigMapRef.Name == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("configMapRef", "name"), "ConfigMap name is required"))
+		}
+		if templateRef.ConfigMapRef.Key == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("configMapRef", "key"), "ConfigMap key is required"))
+		}
+	default:
+		allErrs = append(allErrs, field.Required(fldPath, "either name or configMapRef is required"))
+	}
+
+	return allErrs
+}
+
+// validateServerSelector checks if the server labels and match expressions form a valid label selector
+func validateServerSelector(spec *v1alpha1.ProviderSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	allErrs = append(allErrs, metav1validation.ValidateLabels(spec.ServerLabels, fldPath.Child("serverLabels"))...)
+	for i, expr := range spec.ServerMatchExpressions {
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelectorRequirement(expr, metav1validation.LabelSelectorValidationOptions{}, fldPath.Child("serverMatchExpressions").Index(i))...)
+	}
+
 	return allErrs
 }
 
@@ -115,3 +291,47 @@ func ValidateIPAddressClaim(ipClaim *capiv1beta1.IPAddressClaim, serverClaim *me
 
 	return allErrs
 }
+
+// ValidateIPAddress validates that the IPAddress has the given IP family, if any, and that its prefix and gateway
+// match the family of its address and the given family
+func ValidateIPAddress(ipAddr *capiv1beta1.IPAddress, family corev1.IPFamily) field.ErrorList {
+	var allErrs field.ErrorList
+	fldPath := field.NewPath("spec")
+
+	addr, err := netip.ParseAddr(ipAddr.Spec.Address)
+	if err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("address"), ipAddr.Spec.Address, "address is invalid"))
+		return allErrs
+	}
+
+	switch {
+	case family == corev1.IPv4Protocol && !addr.Is4():
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("address"), ipAddr.Spec.Address, "address must be an IPv4 address"))
+	case family == corev1.IPv6Protocol && !addr.Is6():
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("address"), ipAddr.Spec.Address, "address must be an IPv6 address"))
+	}
+
+	// the prefix has to match both the family of the address and the family of the pool it was allocated from
+	bitLen := addr.BitLen()
+	switch family {
+	case corev1.IPv4Protocol:
+		bitLen = min(bitLen, 32)
+	case corev1.IPv6Protocol:
+		bitLen = min(bitLen, 128)
+	}
+	if ipAddr.Spec.Prefix < 0 || ipAddr.Spec.Prefix > bitLen {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("prefix"), ipAddr.Spec.Prefix, fmt.Sprintf("prefix must be between 0 and %d for address %s", bitLen, addr)))
+	}
+
+	if ipAddr.Spec.Gateway != "" {
+		gateway, err := netip.ParseAddr(ipAddr.Spec.Gateway)
+		switch {
+		case err != nil:
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("gateway"), ipAddr.Spec.Gateway, "gateway is invalid"))
+		case gateway.Is4() != addr.Is4():
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("gateway"), ipAddr.Spec.Gateway, fmt.Sprintf("gateway must have the IP family of address %s", addr)))
+		}
+	}
+
+	return allErrs
+}
@@ -61,6 +61,195 @@ var _ = Describe("Machine", func() {
 			fldPath,
 			ContainElement(field.Invalid(fldPath.Child("spec.dnsServers[0]"), invalidIP, "ip is invalid")),
 		),
+		Entry("valid server match expressions",
+			&v1alpha1.ProviderSpec{
+				ServerMatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "memory-class", Operator: metav1.LabelSelectorOpIn, Values: []string{"256Gi", "512Gi"}},
+					{Key: "rack", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"r12"}},
+					{Key: "gpu", Operator: metav1.LabelSelectorOpExists},
+					{Key: "maintenance", Operator: metav1.LabelSelectorOpDoesNotExist},
+				},
+			},
+			&corev1.Secret{},
+			fldPath,
+			Not(ContainElement(HaveField("Field", HavePrefix("spec.serverMatchExpressions")))),
+		),
+		Entry("server match expression without values",
+			&v1alpha1.ProviderSpec{
+				ServerMatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "rack", Operator: metav1.LabelSelectorOpNotIn},
+				},
+			},
+			&corev1.Secret{},
+			fldPath,
+			ContainElement(field.Required(fldPath.Child("spec.serverMatchExpressions[0].values"), "must be specified when `operator` is 'In' or 'NotIn'")),
+		),
+		Entry("server match expression with forbidden values",
+			&v1alpha1.ProviderSpec{
+				ServerMatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "gpu", Operator: metav1.LabelSelectorOpExists, Values: []string{"true"}},
+				},
+			},
+			&corev1.Secret{},
+			fldPath,
+			ContainElement(field.Forbidden(fldPath.Child("spec.serverMatchExpressions[0].values"), "may not be specified when `operator` is 'Exists' or 'DoesNotExist'")),
+		),
+		Entry("server match expression with unknown operator",
+			&v1alpha1.ProviderSpec{
+				ServerMatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "memory-class", Operator: "GreaterThan", Values: []string{"256Gi"}},
+				},
+			},
+			&corev1.Secret{},
+			fldPath,
+			ContainElement(field.Invalid(fldPath.Child("spec.serverMatchExpressions[0].operator"), metav1.LabelSelectorOperator("GreaterThan"), "not a valid selector operator")),
+		),
+		Entry("server match expression with invalid key",
+			&v1alpha1.ProviderSpec{
+				ServerMatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "invalid key", Operator: metav1.LabelSelectorOpExists},
+				},
+			},
+			&corev1.Secret{},
+			fldPath,
+			ContainElement(HaveField("Field", "spec.serverMatchExpressions[0].key")),
+		),
+		Entry("unknown template name",
+			&v1alpha1.ProviderSpec{
+				TemplateRef: &v1alpha1.TemplateReference{Name: "foo"},
+			},
+			&corev1.Secret{},
+			fldPath,
+			ContainElement(HaveField("Field", "spec.templateRef.name")),
+		),
+		Entry("unknown network format",
+			&v1alpha1.ProviderSpec{
+				NetworkFormat: "netplan",
+			},
+			&corev1.Secret{},
+			fldPath,
+			ContainElement(HaveField("Field", "spec.networkFormat")),
+		),
+		Entry("template name and ConfigMap",
+			&v1alpha1.ProviderSpec{
+				TemplateRef: &v1alpha1.TemplateReference{
+					Name:         "flatcar",
+					ConfigMapRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "foo"}, Key: "template"},
+				},
+			},
+			&corev1.Secret{},
+			fldPath,
+			ContainElement(HaveField("Detail", "name and configMapRef are mutually exclusive")),
+		),
+		Entry("template ConfigMap without key",
+			&v1alpha1.ProviderSpec{
+				TemplateRef: &v1alpha1.TemplateReference{
+					ConfigMapRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "foo"}},
+				},
+			},
+			&corev1.Secret{},
+			fldPath,
+			ContainElement(field.Required(fldPath.Child("spec.templateRef.configMapRef.key"), "ConfigMap key is required")),
+		),
+		Entry("empty template reference",
+			&v1alpha1.ProviderSpec{
+				TemplateRef: &v1alpha1.TemplateReference{},
+			},
+			&corev1.Secret{},
+			fldPath,
+			ContainElement(field.Required(fldPath.Child("spec.templateRef"), "either name or configMapRef is required")),
+		),
+		Entry("invalid ignition Secret key",
+			&v1alpha1.ProviderSpec{
+				IgnitionSecretKey: "foo/bar",
+			},
+			&corev1.Secret{},
+			fldPath,
+			ContainElement(HaveField("Field", "spec.ignitionSecretKey")),
+		),
+		Entry("empty ignition reference",
+			&v1alpha1.ProviderSpec{
+				IgnitionRefs: []v1alpha1.IgnitionReference{{}},
+			},
+			&corev1.Secret{},
+			fldPath,
+			ContainElement(field.Required(fldPath.Child("spec.ignitionRefs[0]"), "one of providerSecretKey, secretRef or configMapRef is required")),
+		),
+		Entry("ignition reference to provider Secret and ConfigMap",
+			&v1alpha1.ProviderSpec{
+				IgnitionRefs: []v1alpha1.IgnitionReference{{
+					ProviderSecretKey: "ignition",
+					ConfigMapRef:      &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "foo"}, Key: "ignition"},
+				}},
+			},
+			&corev1.Secret{Data: map[string][]byte{"ignition": nil}},
+			fldPath,
+			ContainElement(HaveField("Detail", "providerSecretKey, secretRef and configMapRef are mutually exclusive")),
+		),
+		Entry("ignition Secret reference without key",
+			&v1alpha1.ProviderSpec{
+				IgnitionRefs: []v1alpha1.IgnitionReference{{
+					SecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "foo"}},
+				}},
+			},
+			&corev1.Secret{},
+			fldPath,
+			ContainElement(field.Required(fldPath.Child("spec.ignitionRefs[0].secretRef.key"), "Secret key is required")),
+		),
+		Entry("ignition reference to a missing key of the provider Secret",
+			&v1alpha1.ProviderSpec{
+				IgnitionRefs: []v1alpha1.IgnitionReference{{ProviderSecretKey: "ignition"}},
+			},
+			&corev1.Secret{},
+			fldPath,
+			ContainElement(HaveField("Field", "spec.ignitionRefs[0].providerSecretKey")),
+		),
+		Entry("invalid pinned server name",
+			&v1alpha1.ProviderSpec{
+				ServerNames: map[string]string{"machine-0": "Invalid_Server"},
+			},
+			&corev1.Secret{},
+			fldPath,
+			ContainElement(HaveField("Field", "spec.serverNames[machine-0]")),
+		),
+		Entry("server label with invalid value",
+			&v1alpha1.ProviderSpec{
+				ServerLabels: map[string]string{"rack": "not a label value"},
+			},
+			&corev1.Secret{},
+			fldPath,
+			ContainElement(HaveField("Field", "spec.serverLabels")),
+		),
+		Entry("incomplete IPv6 IPAM reference",
+			&v1alpha1.ProviderSpec{
+				IPAMConfig: []v1alpha1.IPAMConfig{{
+					MetadataKey: "dual-stack",
+					IPAMRef:     &v1alpha1.IPAMObjectReference{Name: "pool-v4", Kind: "GlobalInClusterIPPool"},
+					IPv6IPAMRef: &v1alpha1.IPAMObjectReference{Name: "pool-v6"},
+				}},
+			},
+			&corev1.Secret{},
+			fldPath,
+			ContainElement(field.Required(fldPath.Child("spec.ipamConfig[0].ipv6IpamRef.kind"), "kind is required")),
+		),
+		Entry("metadata key colliding with the IPv6 IPAddressClaim of another",
+			&v1alpha1.ProviderSpec{
+				IPAMConfig: []v1alpha1.IPAMConfig{
+					{
+						MetadataKey: "dual-stack",
+						IPAMRef:     &v1alpha1.IPAMObjectReference{Name: "pool-v4", Kind: "GlobalInClusterIPPool"},
+						IPv6IPAMRef: &v1alpha1.IPAMObjectReference{Name: "pool-v6", Kind: "GlobalInClusterIPPool"},
+					},
+					{
+						MetadataKey: "dual-stack-ipv6",
+						IPAMRef:     &v1alpha1.IPAMObjectReference{Name: "pool-other", Kind: "GlobalInClusterIPPool"},
+					},
+				},
+			},
+			&corev1.Secret{},
+			fldPath,
+			ContainElement(HaveField("Field", "spec.ipamConfig[1].metadataKey")),
+		),
 	)
 })
 
@@ -255,3 +444,46 @@ var _ = Describe("ValidateIPAddressClaim", func() {
 		Expect(errs).To(BeEmpty())
 	})
 })
+
+var _ = Describe("ValidateIPAddress", func() {
+	DescribeTable("ValidateIPAddress",
+		func(spec capiv1beta1.IPAddressSpec, family corev1.IPFamily, match types.GomegaMatcher) {
+			Expect(ValidateIPAddress(&capiv1beta1.IPAddress{Spec: spec}, family)).To(match)
+		},
+		Entry("IPv4 address",
+			capiv1beta1.IPAddressSpec{Address: "10.0.0.10", Prefix: 24, Gateway: "10.0.0.1"}, corev1.IPv4Protocol,
+			BeEmpty(),
+		),
+		Entry("IPv6 address of any family",
+			capiv1beta1.IPAddressSpec{Address: "2001:db8::10", Prefix: 64, Gateway: "2001:db8::1"}, corev1.IPFamily(""),
+			BeEmpty(),
+		),
+		Entry("invalid address",
+			capiv1beta1.IPAddressSpec{Address: "foo", Prefix: 24}, corev1.IPFamily(""),
+			ConsistOf(field.Invalid(field.NewPath("spec.address"), "foo", "address is invalid")),
+		),
+		Entry("IPv4 address of an IPv6 pool",
+			capiv1beta1.IPAddressSpec{Address: "10.0.0.10", Prefix: 24}, corev1.IPv6Protocol,
+			ConsistOf(field.Invalid(field.NewPath("spec.address"), "10.0.0.10", "address must be an IPv6 address")),
+		),
+		Entry("IPv4 address with IPv6 prefix",
+			capiv1beta1.IPAddressSpec{Address: "10.0.0.10", Prefix: 64}, corev1.IPv4Protocol,
+			ConsistOf(field.Invalid(field.NewPath("spec.prefix"), 64, "prefix must be between 0 and 32 for address 10.0.0.10")),
+		),
+		Entry("IPv6 address with IPv6 prefix of an IPv4 pool",
+			capiv1beta1.IPAddressSpec{Address: "2001:db8::10", Prefix: 64}, corev1.IPv4Protocol,
+			ConsistOf(
+				field.Invalid(field.NewPath("spec.address"), "2001:db8::10", "address must be an IPv4 address"),
+				field.Invalid(field.NewPath("spec.prefix"), 64, "prefix must be between 0 and 32 for address 2001:db8::10"),
+			),
+		),
+		Entry("IPv6 address with negative prefix",
+			capiv1beta1.IPAddressSpec{Address: "2001:db8::10", Prefix: -1}, corev1.IPv6Protocol,
+			ConsistOf(field.Invalid(field.NewPath("spec.prefix"), -1, "prefix must be between 0 and 128 for address 2001:db8::10")),
+		),
+		Entry("IPv6 address with IPv4 gateway",
+			capiv1beta1.IPAddressSpec{Address: "2001:db8::10", Prefix: 64, Gateway: "10.0.0.1"}, corev1.IPv6Protocol,
+			ConsistOf(field.Invalid(field.NewPath("spec.gateway"), "10.0.0.1", "gateway must have the IP family of address 2001:db8::10")),
+		),
+	)
+})
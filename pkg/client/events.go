@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// EventSource is the component name which is reported as source of the events emitted by the provider
+const EventSource = "machine-controller-manager-provider-ironcore-metal"
+
+// NewEventRecorder returns an event recorder which emits events in the metal cluster until ctx is done.
+// Events are written with the current metal client, so the recorder keeps working across kubeconfig reloads.
+func (p *Provider) NewEventRecorder(ctx context.Context) record.EventRecorder {
+	broadcaster := record.NewBroadcaster(record.WithContext(ctx))
+	broadcaster.StartRecordingToSink(&eventSink{ctx: ctx, provider: p})
+	return broadcaster.NewRecorder(p.s, corev1.EventSource{Component: EventSource})
+}
+
+// eventSink writes events through the metal client of the Provider
+type eventSink struct {
+	ctx      context.Context
+	provider *Provider
+}
+
+func (s *eventSink) Create(event *corev1.Event) (*corev1.Event, error) {
+	if err := s.provider.SyncClient(func(metalClient client.Client) error {
+		return metalClient.Create(s.ctx, event)
+	}); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (s *eventSink) Update(event *corev1.Event) (*corev1.Event, error) {
+	if err := s.provider.SyncClient(func(metalClient client.Client) error {
+		return metalClient.Update(s.ctx, event)
+	}); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (s *eventSink) Patch(oldEvent *corev1.Event, data []byte) (*corev1.Event, error) {
+	event := oldEvent.DeepCopy()
+	if err := s.provider.SyncClient(func(metalClient client.Client) error {
+		return metalClient.Patch(s.ctx, event, client.RawPatch(types.StrategicMergePatchType, data))
+	}); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
@@ -10,6 +10,7 @@ import (
 	"path"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 
 	"github.com/fsnotify/fsnotify"
 
@@ -17,10 +18,12 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/scale/scheme"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
 	capiv1beta1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -28,14 +31,52 @@ import (
 type syncClientFunc func(client client.Client) error
 
 type Provider struct {
-	client         client.Client
-	mu             sync.Mutex
+	// client is swapped atomically on kubeconfig reload, so that callers never block each other
+	client         atomic.Pointer[metalClient]
 	s              *runtime.Scheme
 	kubeconfigPath string
+	// enableCache serves reads of ServerClaims, Servers and IPAddressClaims from an informer cache
+	enableCache bool
 }
 
-func NewProviderAndNamespace(ctx context.Context, kubeconfigPath string) (*Provider, string, error) {
-	cp := &Provider{s: runtime.NewScheme(), kubeconfigPath: kubeconfigPath}
+// metalClient is a client for the metal cluster together with its optional informer cache
+type metalClient struct {
+	client client.Client
+	// stopCache stops the informer cache of the client
+	stopCache context.CancelFunc
+
+	// mu is read locked by the callers of the client, so that its cache is only stopped once they are done
+	mu      sync.RWMutex
+	stopped bool
+}
+
+// acquire read locks the client, it returns false if the client was already stopped
+func (c *metalClient) acquire() bool {
+	c.mu.RLock()
+	if c.stopped {
+		c.mu.RUnlock()
+		return false
+	}
+	return true
+}
+
+func (c *metalClient) release() {
+	c.mu.RUnlock()
+}
+
+// stop waits for the callers of the client to finish and stops its informer cache
+func (c *metalClient) stop() {
+	c.mu.Lock()
+	c.stopped = true
+	c.mu.Unlock()
+
+	if c.stopCache != nil {
+		c.stopCache()
+	}
+}
+
+func NewProviderAndNamespace(ctx context.Context, kubeconfigPath string, enableCache bool) (*Provider, string, error) {
+	cp := &Provider{s: runtime.NewScheme(), kubeconfigPath: kubeconfigPath, enableCache: enableCache}
 	utilruntime.Must(scheme.AddToScheme(cp.s))
 	utilruntime.Must(corev1.AddToScheme(cp.s))
 	utilruntime.Must(metalv1alpha1.AddToScheme(cp.s))
@@ -49,7 +90,7 @@ func NewProviderAndNamespace(ctx context.Context, kubeconfigPath string) (*Provi
 	clientConfig, err := cp.getClientConfig()
 	if err != nil {
 		return nil, "", err
-	} else if err := cp.setMetalClient(clientConfig); err != nil {
+	} else if err := cp.setMetalClient(ctx, clientConfig); err != nil {
 		return nil, "", err
 	}
 	namespace, err := getNamespace(clientConfig)
@@ -61,23 +102,36 @@ func NewProviderAndNamespace(ctx context.Context, kubeconfigPath string) (*Provi
 	return cp, namespace, nil
 }
 
+// SyncClient calls fn with the current metal client, concurrent calls are not serialized
 func (p *Provider) SyncClient(fn syncClientFunc) error {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	if p.client == nil {
-		return fmt.Errorf("client is not initialized")
+	for {
+		current := p.client.Load()
+		if current == nil {
+			return fmt.Errorf("client is not initialized")
+		}
+		// the client was swapped and stopped in the meantime, retry with the new one
+		if !current.acquire() {
+			continue
+		}
+		defer current.release()
+		return fn(current.client)
 	}
-	return fn(p.client)
 }
 
 func (p *Provider) GetClientScheme() *runtime.Scheme {
-	return p.client.Scheme()
+	return p.client.Load().client.Scheme()
 }
 
 func (p *Provider) SetClient(newClient client.Client) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	p.client = newClient
+	p.swapClient(&metalClient{client: newClient})
+}
+
+// swapClient replaces the current metal client and stops the informer cache of the previous one once its callers
+// are done
+func (p *Provider) swapClient(newClient *metalClient) {
+	if old := p.client.Swap(newClient); old != nil && old.stopCache != nil {
+		go old.stop()
+	}
 }
 
 func (p *Provider) getClientConfig() (clientcmd.OverridingClientConfig, error) {
@@ -103,21 +157,67 @@ func getNamespace(clientConfig clientcmd.OverridingClientConfig) (string, error)
 	return namespace, nil
 }
 
-func (p *Provider) setMetalClient(clientConfig clientcmd.OverridingClientConfig) error {
+func (p *Provider) setMetalClient(ctx context.Context, clientConfig clientcmd.OverridingClientConfig) error {
 	restConfig, err := clientConfig.ClientConfig()
 	if err != nil {
 		return fmt.Errorf("unable to get metal cluster rest config: %w", err)
 	}
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	newClient, err := client.New(restConfig, client.Options{Scheme: p.s})
+
+	clientOptions := client.Options{Scheme: p.s}
+	var stopCache context.CancelFunc
+	if p.enableCache {
+		namespace, err := getNamespace(clientConfig)
+		if err != nil {
+			return err
+		}
+		metalCache, cancel, err := p.startCache(ctx, restConfig, namespace)
+		if err != nil {
+			return err
+		}
+		stopCache = cancel
+		clientOptions.Cache = &client.CacheOptions{
+			Reader: metalCache,
+			// only ServerClaims, Servers and IPAddressClaims are read from the cache
+			DisableFor: []client.Object{&corev1.Secret{}, &corev1.ConfigMap{}, &corev1.Node{}, &capiv1beta1.IPAddress{}},
+		}
+	}
+
+	newClient, err := client.New(restConfig, clientOptions)
 	if err != nil {
+		if stopCache != nil {
+			stopCache()
+		}
 		return fmt.Errorf("failed to create client: %w", err)
 	}
-	p.client = newClient
+	p.swapClient(&metalClient{client: newClient, stopCache: stopCache})
 	return nil
 }
 
+// startCache starts an informer cache scoped to the metal namespace which lives until the returned function is called
+func (p *Provider) startCache(ctx context.Context, restConfig *rest.Config, namespace string) (cache.Cache, context.CancelFunc, error) {
+	metalCache, err := cache.New(restConfig, cache.Options{
+		Scheme:            p.s,
+		DefaultNamespaces: map[string]cache.Config{namespace: {}},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create metal cache: %w", err)
+	}
+
+	cacheCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		if err := metalCache.Start(cacheCtx); err != nil {
+			klog.Errorf("Metal cache stopped with an error: %v", err)
+		}
+	}()
+	if !metalCache.WaitForCacheSync(cacheCtx) {
+		cancel()
+		return nil, nil, fmt.Errorf("failed to sync metal cache")
+	}
+
+	klog.V(3).Infof("Metal cache was started for namespace %s", namespace)
+	return metalCache, cancel, nil
+}
+
 func (p *Provider) reloadMetalClientOnConfigChange(ctx context.Context) error {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
@@ -156,7 +256,7 @@ func (p *Provider) reloadMetalClientOnConfigChange(ctx context.Context) error {
 					klog.Warningf("Couldn't get client config when config changed: %v", err)
 					continue
 				}
-				if err := p.setMetalClient(clientConfig); err != nil {
+				if err := p.setMetalClient(ctx, clientConfig); err != nil {
 					klog.Warningf("Couldn't update metal client when config changed: %v", err)
 					continue
 				}
@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// roundTripLatency simulates the latency of a request against the metal cluster
+const roundTripLatency = time.Millisecond
+
+// BenchmarkSyncClient measures the throughput of SyncClient with a growing number of parallel callers.
+// Since callers only share a read lock of the client, ns/op is expected to drop with every increase of parallelism.
+func BenchmarkSyncClient(b *testing.B) {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"}}
+	fakeClient := fake.NewClientBuilder().WithObjects(secret).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			time.Sleep(roundTripLatency)
+			return c.Get(ctx, key, obj, opts...)
+		},
+	}).Build()
+
+	p := &Provider{}
+	p.SetClient(fakeClient)
+
+	for _, parallelism := range []int{1, 8, 64} {
+		b.Run(fmt.Sprintf("parallelism-%d", parallelism), func(b *testing.B) {
+			b.SetParallelism(parallelism)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if err := p.SyncClient(func(c client.Client) error {
+						return c.Get(context.Background(), client.ObjectKeyFromObject(secret), &corev1.Secret{})
+					}); err != nil {
+						b.Error(err)
+					}
+				}
+			})
+		})
+	}
+}
@@ -11,8 +11,14 @@ import (
 	"strings"
 	"time"
 
+	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
 )
 
 const kubeconfigStr = `apiVersion: v1
@@ -45,13 +51,13 @@ func wrap(test func(string, context.Context)) func() {
 var _ = Describe("Provider", func() {
 	When("kubeconfig file is absent", func() {
 		It("returns an error", wrap(func(dirName string, ctx context.Context) {
-			_, _, err := NewProviderAndNamespace(ctx, path.Join(dirName, "kubeconfig"))
+			_, _, err := NewProviderAndNamespace(ctx, path.Join(dirName, "kubeconfig"), false)
 			Expect(err).Should(HaveOccurred())
 			Expect(err.Error()).To(HavePrefix("failed to read metal kubeconfig"))
 		}))
 
 		It("returns an error", wrap(func(dirName string, ctx context.Context) {
-			_, _, err := NewProviderAndNamespace(ctx, path.Join(dirName, "extraDir", "kubeconfig"))
+			_, _, err := NewProviderAndNamespace(ctx, path.Join(dirName, "extraDir", "kubeconfig"), false)
 			Expect(err).Should(HaveOccurred())
 			Expect(err.Error()).To(HavePrefix("unable to add kubeconfig"))
 		}))
@@ -61,7 +67,7 @@ var _ = Describe("Provider", func() {
 		It("returns an error", wrap(func(dirName string, ctx context.Context) {
 			kubeconfig := path.Join(dirName, "kubeconfig")
 			Expect(os.WriteFile(kubeconfig, []byte{}, 0644)).ShouldNot(HaveOccurred())
-			_, _, err := NewProviderAndNamespace(ctx, kubeconfig)
+			_, _, err := NewProviderAndNamespace(ctx, kubeconfig, false)
 			Expect(err).Should(HaveOccurred())
 			Expect(err.Error()).To(HavePrefix("unable to get metal cluster rest config"))
 		}))
@@ -71,7 +77,7 @@ var _ = Describe("Provider", func() {
 		It("returns a default namespace and a client", wrap(func(dirName string, ctx context.Context) {
 			kubeconfig := path.Join(dirName, "kubeconfig")
 			Expect(os.WriteFile(kubeconfig, []byte(kubeconfigStr), 0644)).ShouldNot(HaveOccurred())
-			cp, ns, err := NewProviderAndNamespace(ctx, kubeconfig)
+			cp, ns, err := NewProviderAndNamespace(ctx, kubeconfig, false)
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(ns).To(Equal("default"))
 			Expect(cp).NotTo(BeNil())
@@ -81,25 +87,128 @@ var _ = Describe("Provider", func() {
 			It("updates the client", wrap(func(dirName string, ctx context.Context) {
 				atomicWrite(dirName, "kubeconfig", []byte(kubeconfigStr))
 
-				cp, _, err := NewProviderAndNamespace(ctx, path.Join(dirName, "kubeconfig"))
+				cp, _, err := NewProviderAndNamespace(ctx, path.Join(dirName, "kubeconfig"), false)
 				Expect(err).ShouldNot(HaveOccurred())
 
-				cp.mu.Lock()
-				oldClient := cp.client
-				cp.mu.Unlock()
+				oldClient := cp.client.Load()
 
 				newKubeconfigStr := strings.Replace(kubeconfigStr, "123", "321", 1)
 				atomicWrite(dirName, "kubeconfig", []byte(newKubeconfigStr))
 
 				Eventually(func(g Gomega) {
-					cp.mu.Lock()
-					newClient := cp.client
-					cp.mu.Unlock()
-					g.Expect(newClient).NotTo(Equal(oldClient))
+					g.Expect(cp.client.Load()).NotTo(BeIdenticalTo(oldClient))
 				}).Should(Succeed())
 			}))
 		})
 	})
+
+	When("the client is swapped while it is in use", func() {
+		It("stops the cache of the previous client once its callers are done", func() {
+			cp := &Provider{}
+			stopped := make(chan struct{})
+			cp.swapClient(&metalClient{client: k8sClient, stopCache: func() { close(stopped) }})
+
+			inUse := make(chan struct{})
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				Expect(cp.SyncClient(func(client.Client) error {
+					close(inUse)
+					<-done
+					return nil
+				})).To(Succeed())
+			}()
+			Eventually(inUse).Should(BeClosed())
+
+			cp.SetClient(k8sClient)
+			Consistently(stopped).ShouldNot(BeClosed())
+
+			By("serving new callers with the new client")
+			Expect(cp.SyncClient(func(client.Client) error { return nil })).To(Succeed())
+
+			close(done)
+			Eventually(stopped).Should(BeClosed())
+		})
+	})
+
+	When("the cache is enabled", func() {
+		It("reads ServerClaims from the cache and rebuilds it on kubeconfig change", wrap(func(dirName string, ctx context.Context) {
+			By("creating a user which may only list and watch ServerClaims")
+			clusterRole := &rbacv1.ClusterRole{
+				ObjectMeta: metav1.ObjectMeta{Name: "cache-user"},
+				Rules: []rbacv1.PolicyRule{{
+					APIGroups: []string{metalv1alpha1.GroupVersion.Group},
+					Resources: []string{"serverclaims"},
+					Verbs:     []string{"list", "watch"},
+				}},
+			}
+			Expect(k8sClient.Create(ctx, clusterRole)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, clusterRole)
+			clusterRoleBinding := &rbacv1.ClusterRoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "cache-user"},
+				RoleRef: rbacv1.RoleRef{
+					APIGroup: rbacv1.GroupName,
+					Kind:     "ClusterRole",
+					Name:     clusterRole.Name,
+				},
+				Subjects: []rbacv1.Subject{{
+					APIGroup: rbacv1.GroupName,
+					Kind:     rbacv1.UserKind,
+					Name:     "cache-user",
+				}},
+			}
+			Expect(k8sClient.Create(ctx, clusterRoleBinding)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, clusterRoleBinding)
+
+			user, err := testEnv.AddUser(envtest.User{
+				Name:   "cache-user",
+				Groups: []string{"system:authenticated"},
+			}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			kubeconfigData, err := user.KubeConfig()
+			Expect(err).NotTo(HaveOccurred())
+			atomicWrite(dirName, "kubeconfig", kubeconfigData)
+
+			cp, ns, err := NewProviderAndNamespace(ctx, path.Join(dirName, "kubeconfig"), true)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ns).To(Equal("default"))
+
+			serverClaim := &metalv1alpha1.ServerClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: ns,
+					Name:      "cached-claim",
+				},
+				Spec: metalv1alpha1.ServerClaimSpec{
+					Power: metalv1alpha1.PowerOff,
+				},
+			}
+			Expect(k8sClient.Create(ctx, serverClaim)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, serverClaim)
+
+			Eventually(func() error {
+				return cp.SyncClient(func(c client.Client) error {
+					return c.Get(ctx, client.ObjectKeyFromObject(serverClaim), &metalv1alpha1.ServerClaim{})
+				})
+			}).Should(Succeed())
+
+			By("ensuring that the user may not get the ServerClaim from the API server")
+			liveClient, err := client.New(user.Config(), client.Options{Scheme: k8sClient.Scheme()})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(liveClient.Get(ctx, client.ObjectKeyFromObject(serverClaim), &metalv1alpha1.ServerClaim{})).To(Satisfy(apierrors.IsForbidden))
+
+			oldClient := cp.client.Load()
+
+			atomicWrite(dirName, "kubeconfig", append(kubeconfigData, '\n'))
+
+			Eventually(func(g Gomega) {
+				g.Expect(cp.client.Load()).NotTo(BeIdenticalTo(oldClient))
+			}).Should(Succeed())
+
+			Expect(cp.SyncClient(func(c client.Client) error {
+				return c.Get(ctx, client.ObjectKeyFromObject(serverClaim), &metalv1alpha1.ServerClaim{})
+			})).To(Succeed())
+		}))
+	})
 })
 
 // atomicWrite is a function that mimic behaviour of k8s.io/kubernetes/pkg/volume/util AtomicWriter which is the way k8s controllers save mounted files from secrets.
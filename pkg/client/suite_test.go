@@ -10,6 +10,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ironcore-dev/controller-utils/modutils"
+	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -44,6 +46,11 @@ func TestAPIs(t *testing.T) {
 
 var _ = BeforeSuite(func() {
 	testEnv = &envtest.Environment{
+		CRDDirectoryPaths: []string{
+			modutils.Dir("github.com/ironcore-dev/metal-operator", "config", "crd", "bases"),
+		},
+		ErrorIfCRDPathMissing: true,
+
 		// The BinaryAssetsDirectory is only required if you want to run the tests directly
 		// without call the makefile target test. If not informed it will look for the
 		// default path defined in controller-runtime which is /usr/local/kubebuilder/.
@@ -61,6 +68,8 @@ var _ = BeforeSuite(func() {
 
 	DeferCleanup(testEnv.Stop)
 
+	Expect(metalv1alpha1.AddToScheme(scheme.Scheme)).To(Succeed())
+
 	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme.Scheme})
 	Expect(err).NotTo(HaveOccurred())
 	Expect(k8sClient).NotTo(BeNil())
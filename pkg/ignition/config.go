@@ -15,13 +15,14 @@ import (
 	"github.com/Masterminds/sprig"
 	buconfig "github.com/coreos/butane/config"
 	"github.com/coreos/butane/config/common"
-	"github.com/imdario/mergo"
 	"sigs.k8s.io/yaml"
 )
 
 var (
-	//go:embed ignition.tmpl
-	Template string
+	// ScriptTemplate runs the user data as a shell script on first boot. It is only part of the ignition
+	// if the user data is a shell script or a cloud-config with commands to run.
+	//go:embed script.tmpl
+	ScriptTemplate string
 )
 
 const (
@@ -38,34 +39,114 @@ type Config struct {
 	Ignition         string
 	IgnitionOverride bool
 	DnsServers       []netip.Addr
+	// Server is the Server the Machine is bound to
+	Server Server
+	// Machine is the Machine the ignition is rendered for
+	Machine Machine
+	// MachineClassName is the name of the MachineClass of the Machine
+	MachineClassName string
+	// Addresses are the addresses allocated for the Machine by the metadata key of their IPAM config, IPv4 addresses
+	// come before IPv6 addresses
+	Addresses map[string][]Address
+	// AdditionalIgnitions are merged in the given order after the user data like Ignition. They are merged after
+	// the template has been executed, so that their contents, e.g. credentials, are not interpreted as template.
+	AdditionalIgnitions []string
+	// Networks are rendered into network configuration files in the NetworkFormat
+	Networks []Network
+	// NetworkFormat is the format of the network configuration files, the format of the OS template is used if it
+	// is not set
+	NetworkFormat NetworkFormat
+	// Template is the OS template the ignition is based on, the DefaultTemplateName is used if it is not set
+	Template *OSTemplate
+	// CompressionThreshold is the size of the rendered ignition above which inline file contents are compressed.
+	// Zero disables the compression.
+	CompressionThreshold int
 }
 
+// Server describes the Server a Machine is bound to, e.g. to template per-host settings like a BGP ASN
+type Server struct {
+	Name         string
+	BMCName      string
+	SerialNumber string
+	SystemUUID   string
+}
+
+// Machine describes the Machine an ignition is rendered for
+type Machine struct {
+	Name        string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// Address is an address allocated from an IPAM pool
+type Address struct {
+	IP      string
+	Prefix  int
+	Gateway string
+}
+
+// Render renders the Ignition config of a Machine
 func Render(config *Config) (string, error) {
-	ignitionBase := &map[string]any{}
-	if err := yaml.Unmarshal([]byte(Template), ignitionBase); err != nil {
+	butane, err := RenderButane(config)
+	if err != nil {
 		return "", err
 	}
+	return TranslateButane(butane, config.CompressionThreshold)
+}
 
-	// if ignition was set in providerSpec merge it with our template
-	if config.Ignition != "" {
-		additional := map[string]any{}
+// RenderButane renders the Butane config of a Machine from the OS template, the configuration of the provider spec
+// and the user data, which is translated into the Ignition config by TranslateButane
+func RenderButane(config *Config) (string, error) {
+	userData, err := parseUserData(config.UserData)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse user data: %w", err)
+	}
 
-		if err := yaml.Unmarshal([]byte(config.Ignition), &additional); err != nil {
+	osTemplate := config.Template
+	if osTemplate == nil {
+		var ok bool
+		if osTemplate, ok = GetTemplate(DefaultTemplateName); !ok {
+			return "", fmt.Errorf("default template %q is not registered", DefaultTemplateName)
+		}
+	}
+
+	ignitionBase := &map[string]any{}
+	if err := yaml.Unmarshal([]byte(osTemplate.Content), ignitionBase); err != nil {
+		return "", fmt.Errorf("failed to parse template %q: %w", osTemplate.Name, err)
+	}
+	delete(*ignitionBase, templateNetworkFormatKey)
+
+	// run the user data as a script only if there is something to run
+	if userData.Script != "" {
+		script := map[string]any{}
+		if err := yaml.Unmarshal([]byte(ScriptTemplate), &script); err != nil {
 			return "", err
 		}
+		if err := mergeIgnition(*ignitionBase, script, MergeStrategyMerge); err != nil {
+			return "", fmt.Errorf("failed to merge script configuration with ignition content: %w", err)
+		}
+	}
 
-		// default to append ignition
-		opt := mergo.WithAppendSlice
+	// by default entries of the ignition are merged into the entries of the template with the same key
+	ignitionMergeStrategy := MergeStrategyMerge
 
-		// allow also to fully override
-		if config.IgnitionOverride {
-			opt = mergo.WithOverride
+	// allow also to fully override them
+	if config.IgnitionOverride {
+		ignitionMergeStrategy = MergeStrategyReplace
+	}
+
+	// if ignition was set in providerSpec merge it with our template, the files generated from the configuration
+	// of the provider spec are merged afterwards and replace its entries with the same path
+	if config.Ignition != "" {
+		additional := map[string]any{}
+
+		if err := yaml.Unmarshal([]byte(config.Ignition), &additional); err != nil {
+			return "", err
 		}
 
 		// merge both ignitions
-		err := mergo.Merge(ignitionBase, additional, opt)
-		if err != nil {
-			return "", err
+		if err := mergeIgnition(*ignitionBase, additional, ignitionMergeStrategy); err != nil {
+			return "", fmt.Errorf("failed to merge ignition with template: %w", err)
 		}
 	}
 
@@ -89,7 +170,7 @@ func Render(config *Config) (string, error) {
 		}
 
 		// merge dnsConfiguration with ignition content
-		if err := mergo.Merge(ignitionBase, dnsConf, mergo.WithAppendSlice); err != nil {
+		if err := mergeIgnition(*ignitionBase, dnsConf, MergeStrategyReplace); err != nil {
 			return "", fmt.Errorf("failed to merge dnsServer configuration with igntition content: %w", err)
 		}
 	}
@@ -113,11 +194,36 @@ func Render(config *Config) (string, error) {
 		}
 
 		// merge metaData configuration with ignition content
-		if err := mergo.Merge(ignitionBase, metaDataConf, mergo.WithAppendSlice); err != nil {
+		if err := mergeIgnition(*ignitionBase, metaDataConf, MergeStrategyReplace); err != nil {
 			return "", fmt.Errorf("failed to merge metaData configuration with ignition content: %w", err)
 		}
 	}
 
+	if len(config.Networks) > 0 {
+		networkFormat := config.NetworkFormat
+		if networkFormat == "" {
+			if networkFormat, err = osTemplate.NetworkFormat(); err != nil {
+				return "", err
+			}
+		}
+
+		networkFiles, err := renderNetworkFiles(networkFormat, config.Networks)
+		if err != nil {
+			return "", fmt.Errorf("failed to render network configuration: %w", err)
+		}
+
+		networkConf := map[string]any{
+			"storage": map[string]any{
+				"files": networkFiles,
+			},
+		}
+
+		// merge network configuration with ignition content
+		if err := mergeIgnition(*ignitionBase, networkConf, MergeStrategyReplace); err != nil {
+			return "", fmt.Errorf("failed to merge network configuration with ignition content: %w", err)
+		}
+	}
+
 	mergedIgnition, err := yaml.Marshal(ignitionBase)
 	if err != nil {
 		return "", err
@@ -128,27 +234,71 @@ func Render(config *Config) (string, error) {
 		return "", fmt.Errorf("failed creating ignition file: %w", err)
 	}
 
+	// the template only sees the part of the user data which is run as a script
+	tmplData := *config
+	tmplData.UserData = userData.Script
+
 	buf := bytes.NewBufferString("")
-	err = tmpl.Execute(buf, config)
+	err = tmpl.Execute(buf, &tmplData)
 	if err != nil {
 		return "", fmt.Errorf("failed creating ignition file while executing template: %w", err)
 	}
 
-	ignition, err := renderButane(buf.Bytes())
+	// the configuration derived from the user data is merged after the template has been executed,
+	// so that its contents are not interpreted as template
+	rendered := buf.Bytes()
+	if userData.Config != nil || len(config.AdditionalIgnitions) > 0 {
+		renderedConf := map[string]any{}
+		if err := yaml.Unmarshal(rendered, &renderedConf); err != nil {
+			return "", err
+		}
+		if userData.Config != nil {
+			if err := mergeIgnition(renderedConf, userData.Config, MergeStrategyMerge); err != nil {
+				return "", fmt.Errorf("failed to merge user data configuration with ignition content: %w", err)
+			}
+		}
+		for i, additionalIgnition := range config.AdditionalIgnitions {
+			additional := map[string]any{}
+			if err := yaml.Unmarshal([]byte(additionalIgnition), &additional); err != nil {
+				return "", fmt.Errorf("failed to parse additional ignition %d: %w", i, err)
+			}
+			if err := mergeIgnition(renderedConf, additional, ignitionMergeStrategy); err != nil {
+				return "", fmt.Errorf("failed to merge additional ignition %d with ignition content: %w", i, err)
+			}
+		}
+		if rendered, err = yaml.Marshal(renderedConf); err != nil {
+			return "", err
+		}
+	}
+
+	return string(rendered), nil
+}
+
+// TranslateButane translates a rendered Butane config into an Ignition config. The file contents are compressed if
+// the Ignition config exceeds the compression threshold, zero disables the compression.
+func TranslateButane(butane string, compressionThreshold int) (string, error) {
+	ignition, err := renderButane([]byte(butane), false)
 	if err != nil {
 		return "", err
 	}
 
+	// render again with gzip compressed file contents if the ignition is too large
+	if compressionThreshold > 0 && len(ignition) > compressionThreshold {
+		if ignition, err = renderButane([]byte(butane), true); err != nil {
+			return "", err
+		}
+	}
+
 	return ignition, nil
 }
 
-func renderButane(dataIn []byte) (string, error) {
+func renderButane(dataIn []byte, compress bool) (string, error) {
 	// render by butane to json
 	options := common.TranslateBytesOptions{
 		Raw:    true,
 		Pretty: false,
 	}
-	options.NoResourceAutoCompression = true
+	options.NoResourceAutoCompression = !compress
 	dataOut, report, err := buconfig.TranslateBytes(dataIn, options)
 	if err != nil {
 		return "", fmt.Errorf("failed to render butane config: %w\nreport: %s", err, report.String())
@@ -0,0 +1,268 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package ignition
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// MergeStrategy defines how an entry of a keyed list, e.g. a file or a unit, is merged into an entry with the same
+// key. The strategy of a single entry can be set with its merge_strategy field.
+type MergeStrategy string
+
+const (
+	// MergeStrategyMerge merges the fields of the entry into the existing entry
+	MergeStrategyMerge MergeStrategy = "merge"
+	// MergeStrategyReplace replaces the existing entry
+	MergeStrategyReplace MergeStrategy = "replace"
+	// MergeStrategyRemove removes the existing entry
+	MergeStrategyRemove MergeStrategy = "remove"
+
+	// MergeStrategyField is the field of a list entry which sets its merge strategy, it is removed while merging
+	MergeStrategyField = "merge_strategy"
+)
+
+// listKeys are the fields identifying the entries of the lists of a Butane config like Ignition does
+var listKeys = map[string]string{
+	"passwd.groups":         "name",
+	"passwd.users":          "name",
+	"storage.directories":   "path",
+	"storage.disks":         "device",
+	"storage.files":         "path",
+	"storage.filesystems":   "device",
+	"storage.links":         "path",
+	"storage.luks":          "name",
+	"storage.raid":          "name",
+	"systemd.units":         "name",
+	"systemd.units.dropins": "name",
+}
+
+// setLists are the lists of a Butane config whose values are only added if they are not contained yet
+var setLists = []string{
+	"passwd.users.groups",
+	"passwd.users.ssh_authorized_keys",
+}
+
+// butaneHeaderFields are determined by the OS template and are not overridden
+var butaneHeaderFields = []string{"variant", "version"}
+
+// mergeIgnition merges a Butane config into another like Ignition merges configs. Entries of keyed lists, e.g.
+// files by path or units by name, are merged with the entry of the same key according to their merge strategy, which
+// defaults to the given one. Other lists are appended and the other values of the merged config take precedence,
+// except for the Butane variant and version of the config merged into.
+func mergeIgnition(dst, src map[string]any, defaultStrategy MergeStrategy) error {
+	// normalize the config, e.g. generated from a cloud-config, to the types of a decoded config
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	src = map[string]any{}
+	if err := json.Unmarshal(data, &src); err != nil {
+		return err
+	}
+
+	for _, field := range butaneHeaderFields {
+		if _, ok := dst[field]; ok {
+			delete(src, field)
+		}
+	}
+	return mergeFields(dst, src, "", defaultStrategy)
+}
+
+func mergeFields(dst, src map[string]any, path string, defaultStrategy MergeStrategy) error {
+	for field, srcValue := range src {
+		fieldPath := field
+		if path != "" {
+			fieldPath = path + "." + field
+		}
+
+		dstValue, ok := dst[field]
+		if !ok || dstValue == nil {
+			value, err := newValue(srcValue, fieldPath)
+			if err != nil {
+				return err
+			}
+			dst[field] = value
+			continue
+		}
+
+		switch srcValue := srcValue.(type) {
+		case map[string]any:
+			dstMap, ok := dstValue.(map[string]any)
+			if !ok {
+				return fmt.Errorf("cannot merge object %s into %T", fieldPath, dstValue)
+			}
+			if err := mergeFields(dstMap, srcValue, fieldPath, defaultStrategy); err != nil {
+				return err
+			}
+		case []any:
+			dstList, ok := dstValue.([]any)
+			if !ok {
+				return fmt.Errorf("cannot merge list %s into %T", fieldPath, dstValue)
+			}
+			merged, err := mergeList(dstList, srcValue, fieldPath, defaultStrategy)
+			if err != nil {
+				return err
+			}
+			dst[field] = merged
+		default:
+			dst[field] = srcValue
+		}
+	}
+	return nil
+}
+
+func mergeList(dst, src []any, path string, defaultStrategy MergeStrategy) ([]any, error) {
+	if slices.Contains(setLists, path) {
+		merged := slices.Clone(dst)
+		for _, value := range src {
+			if !slices.ContainsFunc(merged, func(v any) bool { return reflect.DeepEqual(v, value) }) {
+				merged = append(merged, value)
+			}
+		}
+		return merged, nil
+	}
+
+	keyField, keyed := listKeys[path]
+	if !keyed {
+		value, err := newValue(src, path)
+		if err != nil {
+			return nil, err
+		}
+		return append(slices.Clone(dst), value.([]any)...), nil
+	}
+
+	merged := slices.Clone(dst)
+	index := map[string]int{}
+	for i, entry := range merged {
+		if entry, ok := entry.(map[string]any); ok && entry[keyField] != nil {
+			index[fmt.Sprint(entry[keyField])] = i
+		}
+	}
+
+	removed := map[int]struct{}{}
+	for _, entry := range src {
+		srcEntry, ok := entry.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("entries of %s must be objects, got %T", path, entry)
+		}
+		strategy, srcEntry, err := getMergeStrategy(srcEntry, path, defaultStrategy)
+		if err != nil {
+			return nil, err
+		}
+
+		key := fmt.Sprint(srcEntry[keyField])
+		i, exists := index[key]
+		if srcEntry[keyField] == nil || !exists {
+			if strategy == MergeStrategyRemove {
+				continue
+			}
+			value, err := newValue(srcEntry, path)
+			if err != nil {
+				return nil, err
+			}
+			merged = append(merged, value)
+			if srcEntry[keyField] != nil {
+				index[key] = len(merged) - 1
+			}
+			continue
+		}
+
+		switch strategy {
+		case MergeStrategyMerge:
+			dstEntry, ok := merged[i].(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("entries of %s must be objects, got %T", path, merged[i])
+			}
+			if err := mergeFields(dstEntry, srcEntry, path, defaultStrategy); err != nil {
+				return nil, err
+			}
+		case MergeStrategyReplace:
+			value, err := newValue(srcEntry, path)
+			if err != nil {
+				return nil, err
+			}
+			merged[i] = value
+		case MergeStrategyRemove:
+			removed[i] = struct{}{}
+			delete(index, key)
+		}
+	}
+
+	result := make([]any, 0, len(merged))
+	for i, entry := range merged {
+		if _, ok := removed[i]; !ok {
+			result = append(result, entry)
+		}
+	}
+	return result, nil
+}
+
+// getMergeStrategy returns the merge strategy of a list entry and the entry without its merge strategy field
+func getMergeStrategy(entry map[string]any, path string, defaultStrategy MergeStrategy) (MergeStrategy, map[string]any, error) {
+	value, ok := entry[MergeStrategyField]
+	if !ok {
+		return defaultStrategy, entry, nil
+	}
+
+	entry = maps.Clone(entry)
+	delete(entry, MergeStrategyField)
+
+	strategy, _ := value.(string)
+	switch MergeStrategy(strategy) {
+	case MergeStrategyMerge, MergeStrategyReplace, MergeStrategyRemove:
+		return MergeStrategy(strategy), entry, nil
+	default:
+		return "", nil, fmt.Errorf("unknown %s %q of an entry of %s, must be one of %s, %s or %s", MergeStrategyField, value, path, MergeStrategyMerge, MergeStrategyReplace, MergeStrategyRemove)
+	}
+}
+
+// newValue returns a value which is added without merging, merge strategies of its entries are removed
+func newValue(value any, path string) (any, error) {
+	switch value := value.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(value))
+		for field, fieldValue := range value {
+			fieldPath := field
+			if path != "" {
+				fieldPath = path + "." + field
+			}
+			v, err := newValue(fieldValue, fieldPath)
+			if err != nil {
+				return nil, err
+			}
+			result[field] = v
+		}
+		return result, nil
+	case []any:
+		result := make([]any, 0, len(value))
+		for _, entry := range value {
+			if entryMap, ok := entry.(map[string]any); ok {
+				if _, keyed := listKeys[path]; keyed {
+					strategy, stripped, err := getMergeStrategy(entryMap, path, MergeStrategyMerge)
+					if err != nil {
+						return nil, err
+					}
+					if strategy == MergeStrategyRemove {
+						continue
+					}
+					entryMap = stripped
+				}
+				v, err := newValue(entryMap, path)
+				if err != nil {
+					return nil, err
+				}
+				entry = v
+			}
+			result = append(result, entry)
+		}
+		return result, nil
+	default:
+		return value, nil
+	}
+}
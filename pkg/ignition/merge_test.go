@@ -0,0 +1,206 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package ignition
+
+import (
+	"net/netip"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+)
+
+var _ = Describe("Merge", func() {
+	decodeYAML := func(data string) map[string]any {
+		GinkgoHelper()
+		decoded := map[string]any{}
+		Expect(yaml.Unmarshal([]byte(data), &decoded)).To(Succeed())
+		return decoded
+	}
+
+	base := `variant: fcos
+version: 1.3.0
+storage:
+  files:
+    - path: /etc/hostname
+      mode: 420
+      contents:
+        inline: machine-0
+    - path: /etc/foo
+      contents:
+        inline: foo
+systemd:
+  units:
+    - name: foo.service
+      enabled: true
+      dropins:
+        - name: 10-env.conf
+          contents: FOO=bar
+passwd:
+  users:
+    - name: core
+      ssh_authorized_keys: [ssh-ed25519 AAAA]
+`
+
+	It("should merge entries with the same key", func() {
+		dst := decodeYAML(base)
+		Expect(mergeIgnition(dst, decodeYAML(`variant: flatcar
+version: 1.0.0
+storage:
+  files:
+    - path: /etc/hostname
+      contents:
+        inline: custom
+    - path: /etc/bar
+      contents:
+        inline: bar
+systemd:
+  units:
+    - name: foo.service
+      dropins:
+        - name: 10-env.conf
+          contents: FOO=baz
+        - name: 20-env.conf
+          contents: BAR=baz
+passwd:
+  users:
+    - name: core
+      ssh_authorized_keys: [ssh-ed25519 AAAA, ssh-ed25519 BBBB]
+`), MergeStrategyMerge)).To(Succeed())
+
+		Expect(dst).To(Equal(decodeYAML(`variant: fcos
+version: 1.3.0
+storage:
+  files:
+    - path: /etc/hostname
+      mode: 420
+      contents:
+        inline: custom
+    - path: /etc/foo
+      contents:
+        inline: foo
+    - path: /etc/bar
+      contents:
+        inline: bar
+systemd:
+  units:
+    - name: foo.service
+      enabled: true
+      dropins:
+        - name: 10-env.conf
+          contents: FOO=baz
+        - name: 20-env.conf
+          contents: BAR=baz
+passwd:
+  users:
+    - name: core
+      ssh_authorized_keys: [ssh-ed25519 AAAA, ssh-ed25519 BBBB]
+`)))
+	})
+
+	It("should replace and remove entries according to their merge strategy", func() {
+		dst := decodeYAML(base)
+		Expect(mergeIgnition(dst, decodeYAML(`storage:
+  files:
+    - path: /etc/hostname
+      merge_strategy: replace
+      contents:
+        inline: custom
+    - path: /etc/foo
+      merge_strategy: remove
+    - path: /etc/bar
+      merge_strategy: remove
+systemd:
+  units:
+    - name: foo.service
+      enabled: false
+`), MergeStrategyMerge)).To(Succeed())
+
+		Expect(dst).To(SatisfyAll(
+			HaveKeyWithValue("storage", HaveKeyWithValue("files", ConsistOf(
+				map[string]any{"path": "/etc/hostname", "contents": map[string]any{"inline": "custom"}},
+			))),
+			HaveKeyWithValue("systemd", HaveKeyWithValue("units", ConsistOf(SatisfyAll(
+				HaveKeyWithValue("enabled", false),
+				HaveKeyWithValue("dropins", HaveLen(1)),
+			)))),
+		))
+	})
+
+	It("should replace entries by default if the ignition overrides the template", func() {
+		dst := decodeYAML(base)
+		Expect(mergeIgnition(dst, decodeYAML(`systemd:
+  units:
+    - name: foo.service
+      enabled: false
+    - name: bar.service
+      merge_strategy: merge
+      dropins:
+        - name: 10-env.conf
+          merge_strategy: remove
+`), MergeStrategyReplace)).To(Succeed())
+
+		Expect(dst).To(HaveKeyWithValue("systemd", HaveKeyWithValue("units", ConsistOf(
+			map[string]any{"name": "foo.service", "enabled": false},
+			map[string]any{"name": "bar.service", "dropins": []any{}},
+		))))
+	})
+
+	It("should reject unknown merge strategies", func() {
+		Expect(mergeIgnition(decodeYAML(base), decodeYAML(`storage:
+  files:
+    - path: /etc/hostname
+      merge_strategy: foo
+`), MergeStrategyMerge)).To(MatchError(ContainSubstring(`unknown merge_strategy "foo" of an entry of storage.files`)))
+	})
+
+	It("should let the ignition of the provider spec override a file of the template", func() {
+		ignition := render(&Config{
+			Hostname: "machine-0",
+			Ignition: "storage:\n  files:\n    - path: /etc/hostname\n      contents:\n        inline: custom\n",
+		})
+
+		Expect(files(ignition)).To(HaveKeyWithValue("/etc/hostname", "custom"))
+		Expect(ignition["storage"]).To(HaveKeyWithValue("files", ContainElement(SatisfyAll(
+			HaveKeyWithValue("path", "/etc/hostname"),
+			HaveKeyWithValue("overwrite", true),
+		))))
+	})
+
+	It("should let the generated files replace files of the provider spec with the same path", func() {
+		ignition := render(&Config{
+			Hostname:   "machine-0",
+			DnsServers: []netip.Addr{netip.MustParseAddr("10.0.0.53")},
+			Networks: []Network{{
+				Name:       "eth0",
+				MACAddress: "aa:bb:cc:dd:ee:ff",
+				Addresses:  []netip.Prefix{netip.MustParsePrefix("10.0.0.10/24")},
+			}},
+			NetworkFormat: NetworkFormatNetworkd,
+			Ignition: `storage:
+  files:
+    - path: /etc/hostname
+      contents:
+        inline: custom
+    - path: /etc/systemd/resolved.conf.d/dns.conf
+      contents:
+        source: https://example.com/dns.conf
+    - path: /etc/systemd/network/10-eth0.network
+      contents:
+        inline: custom
+`,
+		})
+
+		Expect(files(ignition)).To(SatisfyAll(
+			HaveKeyWithValue("/etc/hostname", "custom"),
+			HaveKeyWithValue("/etc/systemd/resolved.conf.d/dns.conf", "[Resolve]\nDNS=10.0.0.53"),
+			HaveKeyWithValue("/etc/systemd/network/10-eth0.network", ContainSubstring("MACAddress=aa:bb:cc:dd:ee:ff")),
+		))
+		paths := map[string]int{}
+		for _, file := range ignition["storage"].(map[string]any)["files"].([]any) {
+			paths[file.(map[string]any)["path"].(string)]++
+		}
+		Expect(paths).To(HaveEach(1))
+	})
+})
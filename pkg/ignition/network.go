@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package ignition
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// NetworkFormat is the format of the network configuration files of an operating system
+type NetworkFormat string
+
+const (
+	// NetworkFormatNetworkd renders systemd-networkd .network files
+	NetworkFormatNetworkd NetworkFormat = "networkd"
+	// NetworkFormatNetworkManager renders NetworkManager keyfiles
+	NetworkFormatNetworkManager NetworkFormat = "networkmanager"
+
+	networkdDir       = "/etc/systemd/network"
+	networkManagerDir = "/etc/NetworkManager/system-connections"
+	// NetworkManager ignores keyfiles which are readable by other users than root
+	networkManagerFileMode = 0600
+)
+
+var invalidFileNameChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// Network is the static configuration of a network interface, which is matched by its MAC address
+type Network struct {
+	// Name is the name of the interface, it is only used to name the configuration file
+	Name       string
+	MACAddress string
+	Addresses  []netip.Prefix
+	Gateways   []netip.Addr
+}
+
+// networkFormatForVariant returns the network configuration format of the operating systems of a Butane variant,
+// Flatcar uses systemd-networkd while the Fedora and RHEL based variants use NetworkManager. Other variants have no
+// known format, guessing it could e.g. silently disable IPv6 on an image which does not use NetworkManager.
+func networkFormatForVariant(variant string) (NetworkFormat, bool) {
+	switch variant {
+	case "flatcar":
+		return NetworkFormatNetworkd, true
+	case "fcos", "openshift", "r4e", "fiot":
+		return NetworkFormatNetworkManager, true
+	default:
+		return "", false
+	}
+}
+
+// renderNetworkFiles renders the configuration files of the networks as Butane storage files
+func renderNetworkFiles(format NetworkFormat, networks []Network) ([]any, error) {
+	files := make([]any, 0, len(networks))
+	for i, network := range networks {
+		if network.MACAddress == "" {
+			return nil, fmt.Errorf("network %q has no MAC address", network.Name)
+		}
+		name := invalidFileNameChars.ReplaceAllString(network.Name, "-")
+
+		var path, contents string
+		mode := fileMode
+		switch format {
+		case NetworkFormatNetworkd:
+			path = fmt.Sprintf("%s/%02d-%s.network", networkdDir, 10+i, name)
+			contents = renderNetworkdFile(network)
+		case NetworkFormatNetworkManager:
+			path = fmt.Sprintf("%s/%s.nmconnection", networkManagerDir, name)
+			contents = renderNetworkManagerFile(name, network)
+			mode = networkManagerFileMode
+		default:
+			return nil, fmt.Errorf("unknown network format %q", format)
+		}
+
+		files = append(files, map[string]any{
+			"path": path,
+			"mode": mode,
+			"contents": map[string]any{
+				"inline": contents,
+			},
+		})
+	}
+	return files, nil
+}
+
+func renderNetworkdFile(network Network) string {
+	lines := []string{
+		"[Match]",
+		"MACAddress=" + strings.ToLower(network.MACAddress),
+		"",
+		"[Network]",
+	}
+	for _, address := range network.Addresses {
+		lines = append(lines, "Address="+address.String())
+	}
+	for _, gateway := range network.Gateways {
+		lines = append(lines, "Gateway="+gateway.String())
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func renderNetworkManagerFile(name string, network Network) string {
+	lines := []string{
+		"[connection]",
+		"id=" + name,
+		"type=ethernet",
+		"",
+		"[ethernet]",
+		"mac-address=" + strings.ToUpper(network.MACAddress),
+	}
+	for _, family := range []struct {
+		section string
+		is4     bool
+	}{{"ipv4", true}, {"ipv6", false}} {
+		lines = append(lines, "", "["+family.section+"]")
+
+		var addresses []string
+		for _, address := range network.Addresses {
+			if address.Addr().Is4() == family.is4 {
+				addresses = append(addresses, fmt.Sprintf("address%d=%s", len(addresses)+1, address.String()))
+			}
+		}
+		if len(addresses) == 0 {
+			lines = append(lines, "method=disabled")
+			continue
+		}
+		lines = append(lines, "method=manual")
+		lines = append(lines, addresses...)
+		for _, gateway := range network.Gateways {
+			if gateway.Is4() == family.is4 {
+				lines = append(lines, "gateway="+gateway.String())
+				break
+			}
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package ignition
+
+import (
+	"net/netip"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Network", func() {
+	networks := []Network{{
+		Name:       "eth0",
+		MACAddress: "aa:bb:cc:dd:ee:ff",
+		Addresses:  []netip.Prefix{netip.MustParsePrefix("10.0.0.10/24")},
+		Gateways:   []netip.Addr{netip.MustParseAddr("10.0.0.1")},
+	}}
+
+	It("should render systemd-networkd files for Flatcar", func() {
+		flatcar, ok := GetTemplate("flatcar")
+		Expect(ok).To(BeTrue())
+		format, err := flatcar.NetworkFormat()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(format).To(Equal(NetworkFormatNetworkd))
+
+		ignition := render(&Config{Hostname: "machine-0", Template: flatcar, Networks: networks})
+		Expect(files(ignition)).To(HaveKeyWithValue("/etc/systemd/network/10-eth0.network", `[Match]
+MACAddress=aa:bb:cc:dd:ee:ff
+
+[Network]
+Address=10.0.0.10/24
+Gateway=10.0.0.1
+`))
+	})
+
+	It("should render the network format declared by the template", func() {
+		gardenLinux, err := ParseTemplate("gardenlinux", "variant: fcos\nversion: 1.3.0\nnetworkFormat: networkd\n")
+		Expect(err).NotTo(HaveOccurred())
+		format, err := gardenLinux.NetworkFormat()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(format).To(Equal(NetworkFormatNetworkd))
+
+		ignition := render(&Config{Hostname: "machine-0", Template: gardenLinux, Networks: networks})
+		Expect(ignition).NotTo(HaveKey("networkFormat"))
+		Expect(files(ignition)).To(SatisfyAll(
+			HaveKey("/etc/systemd/network/10-eth0.network"),
+			Not(HaveKey("/etc/NetworkManager/system-connections/eth0.nmconnection")),
+		))
+
+		By("rejecting an unsupported network format")
+		_, err = ParseTemplate("custom", "variant: fcos\nversion: 1.3.0\nnetworkFormat: ifupdown\n")
+		Expect(err).To(MatchError(`network format "ifupdown" of template "custom" is not supported`))
+	})
+
+	It("should render NetworkManager keyfiles for Fedora CoreOS", func() {
+		fcos, ok := GetTemplate("fcos")
+		Expect(ok).To(BeTrue())
+		format, err := fcos.NetworkFormat()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(format).To(Equal(NetworkFormatNetworkManager))
+
+		ignition := render(&Config{Hostname: "machine-0", Networks: networks})
+		Expect(files(ignition)).To(HaveKeyWithValue("/etc/NetworkManager/system-connections/eth0.nmconnection", `[connection]
+id=eth0
+type=ethernet
+
+[ethernet]
+mac-address=AA:BB:CC:DD:EE:FF
+
+[ipv4]
+method=manual
+address1=10.0.0.10/24
+gateway=10.0.0.1
+
+[ipv6]
+method=disabled
+`))
+		Expect(ignition["storage"].(map[string]any)["files"]).To(ContainElement(SatisfyAll(
+			HaveKeyWithValue("path", "/etc/NetworkManager/system-connections/eth0.nmconnection"),
+			HaveKeyWithValue("mode", 384.0),
+		)))
+	})
+
+	It("should render both address families of dual-stack networks", func() {
+		ignition := render(&Config{
+			Hostname:   "machine-0",
+			DnsServers: []netip.Addr{netip.MustParseAddr("10.0.0.53"), netip.MustParseAddr("2001:db8::53")},
+			Networks: []Network{{
+				Name:       "eth0",
+				MACAddress: "aa:bb:cc:dd:ee:ff",
+				Addresses:  []netip.Prefix{netip.MustParsePrefix("10.0.0.10/24"), netip.MustParsePrefix("2001:db8::10/64")},
+				Gateways:   []netip.Addr{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("2001:db8::1")},
+			}},
+		})
+		Expect(files(ignition)).To(SatisfyAll(
+			HaveKeyWithValue("/etc/NetworkManager/system-connections/eth0.nmconnection", ContainSubstring(`[ipv4]
+method=manual
+address1=10.0.0.10/24
+gateway=10.0.0.1
+
+[ipv6]
+method=manual
+address1=2001:db8::10/64
+gateway=2001:db8::1
+`)),
+			HaveKeyWithValue("/etc/systemd/resolved.conf.d/dns.conf", "[Resolve]\nDNS=10.0.0.53\nDNS=2001:db8::53"),
+		))
+	})
+
+	It("should render the network format of the config instead of the one of the template", func() {
+		ignition := render(&Config{Hostname: "machine-0", Networks: networks, NetworkFormat: NetworkFormatNetworkd})
+		Expect(files(ignition)).To(SatisfyAll(
+			HaveKey("/etc/systemd/network/10-eth0.network"),
+			Not(HaveKey("/etc/NetworkManager/system-connections/eth0.nmconnection")),
+		))
+	})
+
+	It("should reject networks if the network format of the template is unknown", func() {
+		custom, err := ParseTemplate("custom", "variant: custom\nversion: 1.0.0\n")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = custom.NetworkFormat()
+		Expect(err).To(HaveOccurred())
+
+		_, err = Render(&Config{Hostname: "machine-0", Template: custom, Networks: networks})
+		Expect(err).To(MatchError(`network format of Butane variant "custom" of template "custom" is unknown and must be set explicitly`))
+	})
+
+	It("should reject networks without MAC address", func() {
+		_, err := Render(&Config{Hostname: "machine-0", Networks: []Network{{Name: "eth0"}}})
+		Expect(err).To(MatchError(ContainSubstring(`network "eth0" has no MAC address`)))
+	})
+})
@@ -0,0 +1,249 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package ignition
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+const (
+	// DefaultMaxSize is the default maximum size of an ignition, which leaves room for the metadata of the Secret
+	// it is stored in below the 1 MiB size limit of Secrets
+	DefaultMaxSize = 1000 * 1024
+)
+
+// Split splits an ignition which is larger than maxSize into a pointer config and parts. The first part contains
+// everything except the files and the merged configs, the files are distributed over the following parts. Configs
+// merged inline, e.g. Ignition user data, are split the same way into further parts, configs merged from remote
+// sources are referenced by the pointer config as they are. The pointer config lets Ignition merge the parts in the
+// original order on boot from the URLs returned by partURL, verified by their SHA-512 hash. An ignition which is not
+// larger than maxSize is returned as it is, an oversized ignition cannot be split without partURL.
+func Split(ignition string, maxSize int, partURL func(index int) string) (string, []string, error) {
+	if maxSize <= 0 || len(ignition) <= maxSize {
+		return ignition, nil, nil
+	}
+	if partURL == nil {
+		return "", nil, fmt.Errorf("ignition of %d bytes exceeds the maximum size of %d bytes and its parts cannot be served", len(ignition), maxSize)
+	}
+
+	config := map[string]any{}
+	if err := json.Unmarshal([]byte(ignition), &config); err != nil {
+		return "", nil, fmt.Errorf("failed to parse ignition: %w", err)
+	}
+
+	ignitionSection, _ := config["ignition"].(map[string]any)
+	version, _ := ignitionSection["version"].(string)
+	if version == "" {
+		return "", nil, fmt.Errorf("ignition does not declare its version")
+	}
+	// the pointer config needs the same security and timeout settings to fetch the parts
+	pointerSection := map[string]any{"version": version}
+	for _, key := range []string{"security", "timeouts"} {
+		if value, ok := ignitionSection[key]; ok {
+			pointerSection[key] = value
+		}
+	}
+
+	entries, err := splitConfig(config, maxSize)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var parts []string
+	merge := make([]any, 0, len(entries))
+	for _, entry := range entries {
+		part, ok := entry.(string)
+		if !ok {
+			merge = append(merge, entry)
+			continue
+		}
+		if len(part) > maxSize {
+			return "", nil, fmt.Errorf("part %d of the ignition is %d bytes and exceeds the maximum size of %d bytes", len(parts), len(part), maxSize)
+		}
+		hash := sha512.Sum512([]byte(part))
+		merge = append(merge, map[string]any{
+			"source": partURL(len(parts)),
+			"verification": map[string]any{
+				"hash": "sha512-" + hex.EncodeToString(hash[:]),
+			},
+		})
+		parts = append(parts, part)
+	}
+	pointerSection["config"] = map[string]any{"merge": merge}
+
+	pointer, err := json.Marshal(map[string]any{"ignition": pointerSection})
+	if err != nil {
+		return "", nil, err
+	}
+	if len(pointer) > maxSize {
+		return "", nil, fmt.Errorf("pointer config of %d bytes exceeds the maximum size of %d bytes", len(pointer), maxSize)
+	}
+
+	return string(pointer), parts, nil
+}
+
+// splitConfig splits an Ignition config into the configs which merged in order are equivalent to it. The entries are
+// either parts to be served or config references to be merged as they are.
+func splitConfig(config map[string]any, maxSize int) ([]any, error) {
+	ignitionSection, _ := config["ignition"].(map[string]any)
+	version, _ := ignitionSection["version"].(string)
+	if version == "" {
+		return nil, fmt.Errorf("ignition does not declare its version")
+	}
+
+	var merges []any
+	if configSection, ok := ignitionSection["config"].(map[string]any); ok {
+		merges, _ = configSection["merge"].([]any)
+		delete(configSection, "merge")
+		if len(configSection) == 0 {
+			delete(ignitionSection, "config")
+		}
+	}
+
+	var files []any
+	if storage, ok := config["storage"].(map[string]any); ok {
+		files, _ = storage["files"].([]any)
+		delete(storage, "files")
+		if len(storage) == 0 {
+			delete(config, "storage")
+		}
+	}
+
+	base, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	entries := []any{string(base)}
+
+	filesParts, err := splitFiles(version, files, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range filesParts {
+		entries = append(entries, part)
+	}
+
+	// the merged configs come last, as Ignition merges them on top of the config referencing them
+	for i, merge := range merges {
+		resource, _ := merge.(map[string]any)
+		source, _ := resource["source"].(string)
+		if !strings.HasPrefix(source, "data:") {
+			entries = append(entries, merge)
+			continue
+		}
+
+		mergedConfig, err := decodeMergedConfig(source, resource["compression"])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode merged config %d: %w", i, err)
+		}
+		mergedEntries, err := splitConfig(mergedConfig, maxSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split merged config %d: %w", i, err)
+		}
+		entries = append(entries, mergedEntries...)
+	}
+
+	return entries, nil
+}
+
+// decodeMergedConfig decodes an Ignition config which is merged inline as data URL
+func decodeMergedConfig(source string, compression any) (map[string]any, error) {
+	dataURL, err := dataurl.DecodeString(source)
+	if err != nil {
+		return nil, err
+	}
+
+	data := dataURL.Data
+	switch compression {
+	case nil, "":
+	case "gzip":
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = io.ReadAll(reader); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("compression %q is not supported", compression)
+	}
+
+	config := map[string]any{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// splitFiles distributes the files over as few ignitions of the given version as possible
+func splitFiles(version string, files []any, maxSize int) ([]string, error) {
+	newPart := func(files []any) ([]byte, error) {
+		return json.Marshal(map[string]any{
+			"ignition": map[string]any{"version": version},
+			"storage":  map[string]any{"files": files},
+		})
+	}
+
+	emptyPart, err := newPart([]any{})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		parts        []string
+		current      []any
+		currentSize  = len(emptyPart)
+		flushCurrent = func() error {
+			part, err := newPart(current)
+			if err != nil {
+				return err
+			}
+			parts = append(parts, string(part))
+			current, currentSize = nil, len(emptyPart)
+			return nil
+		}
+	)
+
+	for _, file := range files {
+		fileJSON, err := json.Marshal(file)
+		if err != nil {
+			return nil, err
+		}
+		if len(emptyPart)+len(fileJSON) > maxSize {
+			path, _ := file.(map[string]any)["path"].(string)
+			return nil, fmt.Errorf("file %q of %d bytes does not fit into an ignition part of at most %d bytes", path, len(fileJSON), maxSize)
+		}
+
+		// files in a list are separated by a comma
+		size := len(fileJSON)
+		if len(current) > 0 {
+			size++
+		}
+		if len(current) > 0 && currentSize+size > maxSize {
+			if err := flushCurrent(); err != nil {
+				return nil, err
+			}
+			size = len(fileJSON)
+		}
+		current = append(current, file)
+		currentSize += size
+	}
+
+	if len(current) > 0 {
+		if err := flushCurrent(); err != nil {
+			return nil, err
+		}
+	}
+
+	return parts, nil
+}
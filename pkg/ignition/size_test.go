@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package ignition
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Size", func() {
+	It("should compress file contents of an ignition above the compression threshold", func() {
+		script := strings.Repeat("echo hello\n", 1000)
+
+		ignition := render(&Config{Hostname: "machine-0", UserData: script})
+		Expect(initScript(ignition)).NotTo(HaveKey("compression"))
+
+		ignition = render(&Config{Hostname: "machine-0", UserData: script, CompressionThreshold: 1024})
+		Expect(initScript(ignition)).To(HaveKeyWithValue("compression", "gzip"))
+	})
+
+	It("should not split an ignition which fits", func() {
+		pointer, parts, err := Split(`{"ignition":{"version":"3.2.0"}}`, 1024, partURL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pointer).To(Equal(`{"ignition":{"version":"3.2.0"}}`))
+		Expect(parts).To(BeEmpty())
+	})
+
+	It("should split an oversized ignition into a pointer config and parts", func() {
+		content := newIgnition(3, 500)
+
+		pointer, parts, err := Split(content, 1000, partURL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parts).To(HaveLen(4))
+
+		By("keeping everything except the files in the first part")
+		base := decodeJSON(parts[0])
+		Expect(base).To(HaveKeyWithValue("ignition", HaveKeyWithValue("version", "3.2.0")))
+		Expect(base).To(HaveKey("systemd"))
+		Expect(base).NotTo(HaveKey("storage"))
+
+		By("distributing the files over the other parts")
+		allFiles := map[string]string{}
+		for _, part := range parts[1:] {
+			Expect(len(part)).To(BeNumerically("<=", 1000))
+			partFiles := files(decodeJSON(part))
+			Expect(partFiles).To(HaveLen(1))
+			for path, content := range partFiles {
+				allFiles[path] = content
+			}
+		}
+		Expect(allFiles).To(Equal(files(decodeJSON(content))))
+
+		By("referencing the parts with their hashes from the pointer config")
+		merge := decodeJSON(pointer)["ignition"].(map[string]any)["config"].(map[string]any)["merge"].([]any)
+		Expect(merge).To(HaveLen(len(parts)))
+		for i, part := range parts {
+			hash := sha512.Sum512([]byte(part))
+			Expect(merge[i]).To(Equal(map[string]any{
+				"source": partURL(i),
+				"verification": map[string]any{
+					"hash": "sha512-" + hex.EncodeToString(hash[:]),
+				},
+			}))
+		}
+	})
+
+	It("should split oversized Ignition user data into parts after the parts of the template", func() {
+		userData := newIgnition(3, 500)
+		ignition := render(&Config{Hostname: "machine-0", UserData: userData})
+		content, err := json.Marshal(ignition)
+		Expect(err).NotTo(HaveOccurred())
+
+		pointer, parts, err := Split(string(content), 1500, partURL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decodeJSON(pointer)["ignition"].(map[string]any)["config"].(map[string]any)["merge"]).To(HaveLen(len(parts)))
+
+		By("inlining no merged configs into the parts")
+		partIndexByPath := map[string]int{}
+		unitsPartIndex := -1
+		allFiles := map[string]string{}
+		for i, part := range parts {
+			Expect(len(part)).To(BeNumerically("<=", 1500))
+			decoded := decodeJSON(part)
+			Expect(mergedConfigs(decoded)).To(BeEmpty())
+			if _, ok := decoded["systemd"]; ok {
+				unitsPartIndex = i
+			}
+			for path, content := range files(decoded) {
+				partIndexByPath[path] = i
+				allFiles[path] = content
+			}
+		}
+
+		By("keeping the files of the template and the user data")
+		expectedFiles := files(ignition)
+		for path, content := range files(decodeJSON(userData)) {
+			expectedFiles[path] = content
+		}
+		Expect(allFiles).To(Equal(expectedFiles))
+
+		By("merging the user data on top of the template")
+		Expect(partIndexByPath).To(HaveKey("/etc/hostname"))
+		Expect(unitsPartIndex).To(BeNumerically(">", partIndexByPath["/etc/hostname"]))
+		Expect(partIndexByPath["/etc/file-0"]).To(BeNumerically(">", unitsPartIndex))
+	})
+
+	It("should not split an oversized ignition without part URLs", func() {
+		_, _, err := Split(newIgnition(2, 600), 1000, nil)
+		Expect(err).To(MatchError(ContainSubstring("its parts cannot be served")))
+	})
+
+	It("should reject files which do not fit into a part", func() {
+		_, _, err := Split(newIgnition(1, 2000), 1000, partURL)
+		Expect(err).To(MatchError(ContainSubstring(`file "/etc/file-0" of`)))
+	})
+})
+
+func partURL(index int) string {
+	return fmt.Sprintf("https://ignition.example.com/part-%d", index)
+}
+
+// newIgnition returns an ignition with a unit and the given number of files of the given size
+func newIgnition(count, size int) string {
+	GinkgoHelper()
+	var fileList []any
+	for i := range count {
+		fileList = append(fileList, map[string]any{
+			"path":     fmt.Sprintf("/etc/file-%d", i),
+			"contents": map[string]any{"source": "data:," + strings.Repeat("a", size)},
+		})
+	}
+	data, err := json.Marshal(map[string]any{
+		"ignition": map[string]any{"version": "3.2.0"},
+		"storage":  map[string]any{"files": fileList},
+		"systemd":  map[string]any{"units": []any{map[string]any{"name": "foo.service", "enabled": true}}},
+	})
+	Expect(err).NotTo(HaveOccurred())
+	return string(data)
+}
+
+// initScript returns the contents of the user data script file in the ignition
+func initScript(ignition map[string]any) map[string]any {
+	GinkgoHelper()
+	fileList := ignition["storage"].(map[string]any)["files"].([]any)
+	for _, f := range fileList {
+		if file := f.(map[string]any); file["path"] == "/var/lib/metal-cloud-config/init.sh" {
+			return file["contents"].(map[string]any)
+		}
+	}
+	Fail("ignition has no user data script")
+	return nil
+}
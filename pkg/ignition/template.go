@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package ignition
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// DefaultTemplateName is the name of the OS template which is used if a MachineClass does not reference one
+	DefaultTemplateName = "fcos"
+
+	// templateNetworkFormatKey is the key a template declares the network format of its operating system with, it is
+	// removed from the Butane config before the translation
+	templateNetworkFormatKey = "networkFormat"
+)
+
+var (
+	//go:embed templates/*.tmpl
+	embeddedTemplates embed.FS
+
+	registryMu sync.RWMutex
+	registry   = map[string]*OSTemplate{}
+)
+
+// OSTemplate is the base configuration of an operating system in Butane format, which is rendered into the ignition
+// of a Machine. The Butane variant and version declared by the template define the resulting Ignition spec version.
+type OSTemplate struct {
+	Name    string
+	Variant string
+	Version string
+	Content string
+	// networkFormat is the network format declared by the template, which overrides the format of the Butane variant
+	networkFormat NetworkFormat
+}
+
+// NetworkFormat returns the format of the network configuration files of the operating system of the template,
+// it fails if the template does not declare the format and the format of its Butane variant is unknown
+func (t *OSTemplate) NetworkFormat() (NetworkFormat, error) {
+	if t.networkFormat != "" {
+		return t.networkFormat, nil
+	}
+	format, ok := networkFormatForVariant(t.Variant)
+	if !ok {
+		return "", fmt.Errorf("network format of Butane variant %q of template %q is unknown and must be set explicitly", t.Variant, t.Name)
+	}
+	return format, nil
+}
+
+func init() {
+	entries, err := embeddedTemplates.ReadDir("templates")
+	if err != nil {
+		panic(err)
+	}
+	for _, entry := range entries {
+		content, err := embeddedTemplates.ReadFile(path.Join("templates", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		name := strings.TrimSuffix(entry.Name(), ".tmpl")
+		osTemplate, err := ParseTemplate(name, string(content))
+		if err != nil {
+			panic(err)
+		}
+		registry[name] = osTemplate
+	}
+}
+
+// ParseTemplate parses an OS template and checks that it declares its Butane variant and version. A template may
+// declare the network format of its operating system with the networkFormat key, e.g. for an operating system using
+// systemd-networkd with the fcos variant.
+func ParseTemplate(name, content string) (*OSTemplate, error) {
+	header := struct {
+		Variant       string        `json:"variant"`
+		Version       string        `json:"version"`
+		NetworkFormat NetworkFormat `json:"networkFormat"`
+	}{}
+	if err := yaml.Unmarshal([]byte(content), &header); err != nil {
+		return nil, fmt.Errorf("failed to parse template %q: %w", name, err)
+	}
+	if header.Variant == "" || header.Version == "" {
+		return nil, fmt.Errorf("template %q must declare the Butane variant and version", name)
+	}
+	switch header.NetworkFormat {
+	case "", NetworkFormatNetworkd, NetworkFormatNetworkManager:
+	default:
+		return nil, fmt.Errorf("network format %q of template %q is not supported", header.NetworkFormat, name)
+	}
+
+	return &OSTemplate{
+		Name:          name,
+		Variant:       header.Variant,
+		Version:       header.Version,
+		Content:       content,
+		networkFormat: header.NetworkFormat,
+	}, nil
+}
+
+// RegisterTemplate adds an OS template to the registry of the templates which can be referenced by name
+func RegisterTemplate(name, content string) error {
+	osTemplate, err := ParseTemplate(name, content)
+	if err != nil {
+		return err
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = osTemplate
+	return nil
+}
+
+// GetTemplate returns the registered OS template with the given name
+func GetTemplate(name string) (*OSTemplate, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	osTemplate, ok := registry[name]
+	return osTemplate, ok
+}
+
+// TemplateNames returns the sorted names of the registered OS templates
+func TemplateNames() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package ignition
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OSTemplate", func() {
+	It("should register the embedded templates", func() {
+		Expect(TemplateNames()).To(ContainElements("fcos", "flatcar"))
+
+		fcos, ok := GetTemplate("fcos")
+		Expect(ok).To(BeTrue())
+		Expect(fcos).To(SatisfyAll(
+			HaveField("Variant", "fcos"),
+			HaveField("Version", "1.3.0"),
+		))
+
+		flatcar, ok := GetTemplate("flatcar")
+		Expect(ok).To(BeTrue())
+		Expect(flatcar).To(SatisfyAll(
+			HaveField("Variant", "flatcar"),
+			HaveField("Version", "1.0.0"),
+		))
+	})
+
+	It("should reject templates without Butane variant and version", func() {
+		_, err := ParseTemplate("foo", "storage:\n  files: []\n")
+		Expect(err).To(MatchError(`template "foo" must declare the Butane variant and version`))
+	})
+
+	It("should render the ignition based on the selected template", func() {
+		ignition := render(&Config{Hostname: "machine-0", UserData: "abcd"})
+		Expect(ignition).To(HaveKeyWithValue("ignition", HaveKeyWithValue("version", "3.2.0")))
+
+		flatcar, _ := GetTemplate("flatcar")
+		ignition = render(&Config{Hostname: "machine-0", UserData: "abcd", Template: flatcar})
+		Expect(ignition).To(HaveKeyWithValue("ignition", HaveKeyWithValue("version", "3.3.0")))
+		Expect(files(ignition)).To(SatisfyAll(
+			HaveKeyWithValue("/etc/hostname", "machine-0\n"),
+			HaveKeyWithValue("/var/lib/metal-cloud-config/init.sh", "abcd\n"),
+		))
+
+		custom, err := ParseTemplate("custom", "variant: fcos\nversion: 1.4.0\nstorage:\n  files:\n    - path: /etc/os-hostname\n      contents:\n        inline: \"{{ .Hostname }}\"\n")
+		Expect(err).NotTo(HaveOccurred())
+		ignition = render(&Config{Hostname: "machine-0", UserData: "abcd", Template: custom})
+		Expect(ignition).To(HaveKeyWithValue("ignition", HaveKeyWithValue("version", "3.3.0")))
+		Expect(files(ignition)).To(HaveKeyWithValue("/etc/os-hostname", "machine-0"))
+	})
+
+	It("should provide the Server, the Machine and its addresses to the template", func() {
+		ignition := render(&Config{
+			Hostname: "machine-0",
+			Ignition: `storage:
+  files:
+    - path: /etc/machine-info
+      contents:
+        inline: "{{ .Server.Name }} {{ .Server.BMCName }} {{ .Server.SerialNumber }} {{ .Server.SystemUUID }} {{ .MachineClassName }}"
+    - path: /etc/bgp.conf
+      contents:
+        inline: 'asn={{ index .Machine.Labels "asn" }} rack={{ index .Machine.Annotations "rack" }} ip={{ (index .Addresses "pxe" 0).IP }}/{{ (index .Addresses "pxe" 0).Prefix }}'
+`,
+			Server: Server{
+				Name:         "server-0",
+				BMCName:      "bmc-0",
+				SerialNumber: "1234",
+				SystemUUID:   "5678",
+			},
+			Machine: Machine{
+				Name:        "machine-0",
+				Labels:      map[string]string{"asn": "65001"},
+				Annotations: map[string]string{"rack": "r1"},
+			},
+			MachineClassName: "machine-class",
+			Addresses: map[string][]Address{
+				"pxe": {{IP: "10.0.0.1", Prefix: 24, Gateway: "10.0.0.254"}},
+			},
+		})
+
+		Expect(files(ignition)).To(SatisfyAll(
+			HaveKeyWithValue("/etc/machine-info", "server-0 bmc-0 1234 5678 machine-class"),
+			HaveKeyWithValue("/etc/bgp.conf", "asn=65001 rack=r1 ip=10.0.0.1/24"),
+		))
+	})
+})
@@ -0,0 +1,487 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package ignition
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// UserDataFormat is the format of the user data passed to a Machine
+type UserDataFormat string
+
+const (
+	// UserDataFormatScript is a shell script which is run on first boot
+	UserDataFormatScript UserDataFormat = "Script"
+	// UserDataFormatIgnition is a complete Ignition config in JSON
+	UserDataFormatIgnition UserDataFormat = "Ignition"
+	// UserDataFormatButane is a Butane config which is translated to Ignition
+	UserDataFormatButane UserDataFormat = "Butane"
+	// UserDataFormatCloudConfig is a cloud-init or coreos-cloudinit #cloud-config document
+	UserDataFormatCloudConfig UserDataFormat = "CloudConfig"
+)
+
+const (
+	cloudConfigHeader  = "#cloud-config"
+	defaultUser        = "core"
+	updateConfFile     = "/etc/flatcar/update.conf"
+	defaultFileMode    = 0644
+	runCmdScriptHeader = "#!/bin/sh\nset -e\n"
+)
+
+// userData is the user data of a Machine split into the part which is run as a script on first boot
+// and the part which is merged into the ignition as Butane configuration
+type userData struct {
+	Script string
+	Config map[string]any
+}
+
+// DetectUserDataFormat returns the format of the user data. Everything which is neither a cloud-config,
+// an Ignition config nor a Butane config is treated as a shell script.
+func DetectUserDataFormat(data string) UserDataFormat {
+	trimmed := strings.TrimSpace(data)
+
+	switch {
+	case strings.HasPrefix(trimmed, cloudConfigHeader):
+		return UserDataFormatCloudConfig
+	case strings.HasPrefix(trimmed, "#!"):
+		return UserDataFormatScript
+	case strings.HasPrefix(trimmed, "{"):
+		config := struct {
+			Ignition struct {
+				Version string `json:"version"`
+			} `json:"ignition"`
+		}{}
+		if err := json.Unmarshal([]byte(trimmed), &config); err == nil && config.Ignition.Version != "" {
+			return UserDataFormatIgnition
+		}
+		return UserDataFormatScript
+	}
+
+	config := map[string]any{}
+	if err := yaml.Unmarshal([]byte(trimmed), &config); err != nil {
+		return UserDataFormatScript
+	}
+	variant, variantOK := config["variant"].(string)
+	version, versionOK := config["version"].(string)
+	if variantOK && versionOK && variant != "" && version != "" {
+		return UserDataFormatButane
+	}
+	return UserDataFormatScript
+}
+
+// parseUserData converts the user data into a script and a Butane configuration according to its format
+func parseUserData(data string) (*userData, error) {
+	switch DetectUserDataFormat(data) {
+	case UserDataFormatIgnition:
+		return &userData{Config: mergeConfig(strings.TrimSpace(data))}, nil
+	case UserDataFormatButane:
+		ignitionJSON, err := renderButane([]byte(data), false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to translate Butane user data: %w", err)
+		}
+		return &userData{Config: mergeConfig(ignitionJSON)}, nil
+	case UserDataFormatCloudConfig:
+		script, config, err := convertCloudConfig([]byte(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert cloud-config user data: %w", err)
+		}
+		return &userData{Script: script, Config: config}, nil
+	default:
+		return &userData{Script: data}, nil
+	}
+}
+
+// mergeConfig returns a Butane configuration which lets Ignition merge the given Ignition config on boot
+func mergeConfig(ignitionJSON string) map[string]any {
+	return map[string]any{
+		"ignition": map[string]any{
+			"config": map[string]any{
+				"merge": []any{map[string]any{
+					"inline": ignitionJSON,
+				}},
+			},
+		},
+	}
+}
+
+// cloudConfig is the subset of cloud-init and coreos-cloudinit which can be expressed with Ignition
+type cloudConfig struct {
+	WriteFiles        []cloudConfigFile `json:"write_files,omitempty"`
+	Users             []cloudConfigUser `json:"users,omitempty"`
+	SSHAuthorizedKeys []string          `json:"ssh_authorized_keys,omitempty"`
+	RunCmd            []cloudConfigCmd  `json:"runcmd,omitempty"`
+	CoreOS            cloudConfigCoreOS `json:"coreos,omitempty"`
+}
+
+type cloudConfigFile struct {
+	Path        string                 `json:"path"`
+	Content     string                 `json:"content,omitempty"`
+	Encoding    string                 `json:"encoding,omitempty"`
+	Permissions cloudConfigPermissions `json:"permissions,omitempty"`
+	Owner       string                 `json:"owner,omitempty"`
+	Append      bool                   `json:"append,omitempty"`
+}
+
+type cloudConfigUser struct {
+	Name              string            `json:"name"`
+	Gecos             string            `json:"gecos,omitempty"`
+	Homedir           string            `json:"homedir,omitempty"`
+	NoCreateHome      bool              `json:"no_create_home,omitempty"`
+	PrimaryGroup      string            `json:"primary_group,omitempty"`
+	Groups            cloudConfigGroups `json:"groups,omitempty"`
+	Passwd            string            `json:"passwd,omitempty"`
+	Shell             string            `json:"shell,omitempty"`
+	System            bool              `json:"system,omitempty"`
+	UID               *int              `json:"uid,omitempty"`
+	SSHAuthorizedKeys []string          `json:"ssh_authorized_keys,omitempty"`
+}
+
+type cloudConfigCoreOS struct {
+	Units  []cloudConfigUnit  `json:"units,omitempty"`
+	Update *cloudConfigUpdate `json:"update,omitempty"`
+}
+
+type cloudConfigUnit struct {
+	Name    string              `json:"name"`
+	Content string              `json:"content,omitempty"`
+	Enable  bool                `json:"enable,omitempty"`
+	Command string              `json:"command,omitempty"`
+	Mask    bool                `json:"mask,omitempty"`
+	DropIns []cloudConfigDropIn `json:"drop_ins,omitempty"`
+}
+
+type cloudConfigDropIn struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+type cloudConfigUpdate struct {
+	RebootStrategy string `json:"reboot_strategy,omitempty"`
+	Group          string `json:"group,omitempty"`
+	Server         string `json:"server,omitempty"`
+}
+
+// cloudConfigPermissions is an octal file mode given either as string or as number
+type cloudConfigPermissions int
+
+func (p *cloudConfigPermissions) UnmarshalJSON(data []byte) error {
+	var mode string
+	if err := json.Unmarshal(data, &mode); err != nil {
+		var number int
+		if err := json.Unmarshal(data, &number); err != nil {
+			return fmt.Errorf("permissions must be an octal string or a number: %w", err)
+		}
+		*p = cloudConfigPermissions(number)
+		return nil
+	}
+	parsed, err := strconv.ParseInt(mode, 8, 32)
+	if err != nil {
+		return fmt.Errorf("permissions %q are not octal: %w", mode, err)
+	}
+	*p = cloudConfigPermissions(parsed)
+	return nil
+}
+
+// cloudConfigGroups are the supplementary groups of a user given either as comma separated string or as list
+type cloudConfigGroups []string
+
+func (g *cloudConfigGroups) UnmarshalJSON(data []byte) error {
+	var groups string
+	if err := json.Unmarshal(data, &groups); err != nil {
+		var list []string
+		if err := json.Unmarshal(data, &list); err != nil {
+			return fmt.Errorf("groups must be a comma separated string or a list: %w", err)
+		}
+		*g = list
+		return nil
+	}
+	for group := range strings.SplitSeq(groups, ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			*g = append(*g, group)
+		}
+	}
+	return nil
+}
+
+// cloudConfigCmd is a command given either as shell string or as list of arguments
+type cloudConfigCmd string
+
+func (c *cloudConfigCmd) UnmarshalJSON(data []byte) error {
+	var cmd string
+	if err := json.Unmarshal(data, &cmd); err != nil {
+		var args []string
+		if err := json.Unmarshal(data, &args); err != nil {
+			return fmt.Errorf("command must be a string or a list of arguments: %w", err)
+		}
+		quoted := make([]string, 0, len(args))
+		for _, arg := range args {
+			quoted = append(quoted, "'"+strings.ReplaceAll(arg, "'", `'\''`)+"'")
+		}
+		*c = cloudConfigCmd(strings.Join(quoted, " "))
+		return nil
+	}
+	*c = cloudConfigCmd(cmd)
+	return nil
+}
+
+// convertCloudConfig converts a cloud-config into a script of its commands and a Butane configuration of its
+// files, units and users. Keys which have no equivalent in Ignition, e.g. packages, are ignored with a warning.
+func convertCloudConfig(data []byte) (string, map[string]any, error) {
+	raw := map[string]any{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return "", nil, err
+	}
+
+	// coreos-cloudinit accepts dashes and underscores in keys alike
+	normalizedRaw := normalizeCloudConfigKeys(raw).(map[string]any)
+	if keys := unsupportedCloudConfigKeys(normalizedRaw); len(keys) > 0 {
+		klog.InfoS("Ignoring cloud-config keys without Ignition equivalent", "keys", keys)
+	}
+	normalized, err := json.Marshal(normalizedRaw)
+	if err != nil {
+		return "", nil, err
+	}
+
+	config := &cloudConfig{}
+	if err := json.Unmarshal(normalized, config); err != nil {
+		return "", nil, err
+	}
+
+	files := []any{}
+	for i, file := range config.WriteFiles {
+		butaneFile, err := convertCloudConfigFile(file)
+		if err != nil {
+			return "", nil, fmt.Errorf("write_files[%d]: %w", i, err)
+		}
+		files = append(files, butaneFile)
+	}
+	if update := config.CoreOS.Update; update != nil {
+		files = append(files, convertCloudConfigUpdate(update))
+	}
+
+	units := []any{}
+	for _, unit := range config.CoreOS.Units {
+		units = append(units, convertCloudConfigUnit(unit))
+	}
+
+	users := []any{}
+	for _, user := range config.Users {
+		users = append(users, convertCloudConfigUser(user))
+	}
+	if len(config.SSHAuthorizedKeys) > 0 {
+		users = append(users, map[string]any{
+			"name":                defaultUser,
+			"ssh_authorized_keys": config.SSHAuthorizedKeys,
+		})
+	}
+
+	butane := map[string]any{}
+	if len(files) > 0 {
+		butane["storage"] = map[string]any{"files": files}
+	}
+	if len(units) > 0 {
+		butane["systemd"] = map[string]any{"units": units}
+	}
+	if len(users) > 0 {
+		butane["passwd"] = map[string]any{"users": users}
+	}
+
+	var script string
+	if len(config.RunCmd) > 0 {
+		var sb strings.Builder
+		sb.WriteString(runCmdScriptHeader)
+		for _, cmd := range config.RunCmd {
+			sb.WriteString(string(cmd))
+			sb.WriteString("\n")
+		}
+		script = sb.String()
+	}
+
+	return script, butane, nil
+}
+
+// unsupportedCloudConfigKeys returns the sorted top-level keys of a normalized cloud-config which are not converted
+func unsupportedCloudConfigKeys(raw map[string]any) []string {
+	supported := []string{"write_files", "users", "ssh_authorized_keys", "runcmd", "coreos"}
+	var keys []string
+	for key := range raw {
+		if !slices.Contains(supported, key) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func normalizeCloudConfigKeys(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		normalized := make(map[string]any, len(v))
+		for key, value := range v {
+			normalized[strings.ReplaceAll(key, "-", "_")] = normalizeCloudConfigKeys(value)
+		}
+		return normalized
+	case []any:
+		normalized := make([]any, 0, len(v))
+		for _, value := range v {
+			normalized = append(normalized, normalizeCloudConfigKeys(value))
+		}
+		return normalized
+	default:
+		return value
+	}
+}
+
+func convertCloudConfigFile(file cloudConfigFile) (map[string]any, error) {
+	if file.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+
+	mode := int(file.Permissions)
+	if mode == 0 {
+		mode = defaultFileMode
+	}
+
+	contents := map[string]any{}
+	switch file.Encoding {
+	case "":
+		contents["inline"] = file.Content
+	case "b64", "base64":
+		decoded, err := base64.StdEncoding.DecodeString(file.Content)
+		if err != nil {
+			return nil, fmt.Errorf("content of %q is not base64 encoded: %w", file.Path, err)
+		}
+		if utf8.Valid(decoded) {
+			contents["inline"] = string(decoded)
+		} else {
+			contents["source"] = "data:;base64," + file.Content
+		}
+	case "gz+b64", "gz+base64", "gzip+b64", "gzip+base64":
+		if _, err := base64.StdEncoding.DecodeString(file.Content); err != nil {
+			return nil, fmt.Errorf("content of %q is not base64 encoded: %w", file.Path, err)
+		}
+		contents["source"] = "data:;base64," + file.Content
+		contents["compression"] = "gzip"
+	default:
+		return nil, fmt.Errorf("encoding %q of %q is not supported", file.Encoding, file.Path)
+	}
+
+	butaneFile := map[string]any{
+		"path": file.Path,
+		"mode": mode,
+	}
+	if file.Append {
+		butaneFile["append"] = []any{contents}
+	} else {
+		butaneFile["overwrite"] = true
+		butaneFile["contents"] = contents
+	}
+
+	if file.Owner != "" {
+		user, group, _ := strings.Cut(file.Owner, ":")
+		if user != "" {
+			butaneFile["user"] = map[string]any{"name": user}
+		}
+		if group != "" {
+			butaneFile["group"] = map[string]any{"name": group}
+		}
+	}
+
+	return butaneFile, nil
+}
+
+func convertCloudConfigUpdate(update *cloudConfigUpdate) map[string]any {
+	var lines []string
+	if update.Group != "" {
+		lines = append(lines, "GROUP="+update.Group)
+	}
+	if update.Server != "" {
+		lines = append(lines, "SERVER="+update.Server)
+	}
+	if update.RebootStrategy != "" {
+		lines = append(lines, "REBOOT_STRATEGY="+update.RebootStrategy)
+	}
+
+	return map[string]any{
+		"path":      updateConfFile,
+		"mode":      defaultFileMode,
+		"overwrite": true,
+		"contents": map[string]any{
+			"inline": strings.Join(lines, "\n") + "\n",
+		},
+	}
+}
+
+func convertCloudConfigUnit(unit cloudConfigUnit) map[string]any {
+	butaneUnit := map[string]any{
+		"name": unit.Name,
+	}
+	if unit.Content != "" {
+		butaneUnit["contents"] = unit.Content
+	}
+	// Ignition cannot start units, units which should be started are enabled to start on boot instead
+	if unit.Enable || slices.Contains([]string{"start", "restart", "reload-or-restart", "try-restart"}, unit.Command) {
+		butaneUnit["enabled"] = true
+	}
+	if unit.Mask {
+		butaneUnit["mask"] = true
+	}
+	if len(unit.DropIns) > 0 {
+		dropins := make([]any, 0, len(unit.DropIns))
+		for _, dropIn := range unit.DropIns {
+			dropins = append(dropins, map[string]any{
+				"name":     dropIn.Name,
+				"contents": dropIn.Content,
+			})
+		}
+		butaneUnit["dropins"] = dropins
+	}
+	return butaneUnit
+}
+
+func convertCloudConfigUser(user cloudConfigUser) map[string]any {
+	butaneUser := map[string]any{
+		"name": user.Name,
+	}
+	if user.Gecos != "" {
+		butaneUser["gecos"] = user.Gecos
+	}
+	if user.Homedir != "" {
+		butaneUser["home_dir"] = user.Homedir
+	}
+	if user.NoCreateHome {
+		butaneUser["no_create_home"] = true
+	}
+	if user.PrimaryGroup != "" {
+		butaneUser["primary_group"] = user.PrimaryGroup
+	}
+	if len(user.Groups) > 0 {
+		butaneUser["groups"] = []string(user.Groups)
+	}
+	if user.Passwd != "" {
+		butaneUser["password_hash"] = user.Passwd
+	}
+	if user.Shell != "" {
+		butaneUser["shell"] = user.Shell
+	}
+	if user.System {
+		butaneUser["system"] = true
+	}
+	if user.UID != nil {
+		butaneUser["uid"] = *user.UID
+	}
+	if len(user.SSHAuthorizedKeys) > 0 {
+		butaneUser["ssh_authorized_keys"] = user.SSHAuthorizedKeys
+	}
+	return butaneUser
+}
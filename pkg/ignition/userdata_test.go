@@ -0,0 +1,235 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package ignition
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	ignitionUserData = `{"ignition":{"version":"3.4.0"},"storage":{"files":[{"path":"/etc/foo","contents":{"source":"data:,bar"}}]}}`
+	butaneUserData   = `variant: fcos
+version: 1.3.0
+storage:
+  files:
+    - path: /etc/foo
+      contents:
+        inline: bar
+`
+	cloudConfigUserData = `#cloud-config
+write_files:
+  - path: /etc/foo
+    permissions: "0600"
+    owner: root:wheel
+    encoding: b64
+    content: YmFy
+  - path: /etc/template
+    content: "{{ .Hostname }}"
+coreos:
+  update:
+    reboot-strategy: "off"
+  units:
+    - name: foo.service
+      command: start
+      content: |
+        [Service]
+        ExecStart=/usr/bin/foo
+      drop-ins:
+        - name: 10-env.conf
+          content: |
+            [Service]
+            Environment=FOO=bar
+users:
+  - name: xyz
+    groups: wheel, docker
+ssh_authorized_keys:
+  - ssh-ed25519 AAAA
+runcmd:
+  - echo hello
+  - [ls, -l, "it's"]
+`
+)
+
+var _ = Describe("UserData", func() {
+	DescribeTable("DetectUserDataFormat",
+		func(userData string, format UserDataFormat) {
+			Expect(DetectUserDataFormat(userData)).To(Equal(format))
+		},
+		Entry("plain script", "abcd", UserDataFormatScript),
+		Entry("script with shebang", "#!/bin/bash\necho hello", UserDataFormatScript),
+		Entry("empty user data", "", UserDataFormatScript),
+		Entry("Ignition config", ignitionUserData, UserDataFormatIgnition),
+		Entry("JSON without Ignition version", `{"foo":"bar"}`, UserDataFormatScript),
+		Entry("Butane config", butaneUserData, UserDataFormatButane),
+		Entry("cloud-config", cloudConfigUserData, UserDataFormatCloudConfig),
+	)
+
+	It("should run a script as shell script", func() {
+		ignition := render(&Config{Hostname: "machine-0", UserData: "abcd"})
+
+		Expect(files(ignition)).To(HaveKeyWithValue("/var/lib/metal-cloud-config/init.sh", "abcd\n"))
+		Expect(ignition).To(HaveKeyWithValue("systemd", HaveKeyWithValue("units", ContainElement(HaveKeyWithValue("name", "cloud-config-init.service")))))
+	})
+
+	It("should merge an Ignition config", func() {
+		ignition := render(&Config{Hostname: "machine-0", UserData: ignitionUserData})
+
+		Expect(mergedConfigs(ignition)).To(ConsistOf(ignitionUserData))
+		Expect(files(ignition)).To(SatisfyAll(
+			HaveKeyWithValue("/etc/hostname", "machine-0\n"),
+			Not(HaveKey("/var/lib/metal-cloud-config/init.sh")),
+		))
+		Expect(ignition).NotTo(HaveKey("systemd"))
+	})
+
+	It("should translate and merge a Butane config", func() {
+		ignition := render(&Config{Hostname: "machine-0", UserData: butaneUserData})
+
+		merged := mergedConfigs(ignition)
+		Expect(merged).To(HaveLen(1))
+		Expect(decodeJSON(merged[0])).To(SatisfyAll(
+			HaveKeyWithValue("ignition", HaveKeyWithValue("version", "3.2.0")),
+			HaveKeyWithValue("storage", HaveKeyWithValue("files", ContainElement(HaveKeyWithValue("path", "/etc/foo")))),
+		))
+		Expect(files(ignition)).NotTo(HaveKey("/var/lib/metal-cloud-config/init.sh"))
+	})
+
+	It("should convert a cloud-config into files, units, users and a script", func() {
+		ignition := render(&Config{Hostname: "machine-0", UserData: cloudConfigUserData})
+
+		Expect(files(ignition)).To(SatisfyAll(
+			HaveKeyWithValue("/etc/foo", "bar"),
+			HaveKeyWithValue("/etc/template", "{{ .Hostname }}"),
+			HaveKeyWithValue("/etc/flatcar/update.conf", "REBOOT_STRATEGY=off\n"),
+			HaveKeyWithValue("/var/lib/metal-cloud-config/init.sh", "#!/bin/sh\nset -e\necho hello\n'ls' '-l' 'it'\\''s'\n"),
+		))
+		Expect(ignition["storage"]).To(HaveKeyWithValue("files", ContainElement(SatisfyAll(
+			HaveKeyWithValue("path", "/etc/foo"),
+			HaveKeyWithValue("mode", 384.0),
+			HaveKeyWithValue("user", HaveKeyWithValue("name", "root")),
+			HaveKeyWithValue("group", HaveKeyWithValue("name", "wheel")),
+		))))
+		Expect(ignition["systemd"]).To(HaveKeyWithValue("units", ContainElements(
+			HaveKeyWithValue("name", "cloud-config-init.service"),
+			SatisfyAll(
+				HaveKeyWithValue("name", "foo.service"),
+				HaveKeyWithValue("enabled", true),
+				HaveKeyWithValue("contents", "[Service]\nExecStart=/usr/bin/foo\n"),
+				HaveKeyWithValue("dropins", ConsistOf(HaveKeyWithValue("name", "10-env.conf"))),
+			),
+		)))
+		Expect(ignition["passwd"]).To(HaveKeyWithValue("users", ConsistOf(
+			SatisfyAll(
+				HaveKeyWithValue("name", "xyz"),
+				HaveKeyWithValue("groups", ConsistOf("wheel", "docker")),
+			),
+			SatisfyAll(
+				HaveKeyWithValue("name", "core"),
+				HaveKeyWithValue("sshAuthorizedKeys", ConsistOf("ssh-ed25519 AAAA")),
+			),
+		)))
+	})
+
+	It("should merge additional ignitions in order without interpreting them as template", func() {
+		ignition := render(&Config{
+			Hostname: "machine-0",
+			UserData: "abcd",
+			AdditionalIgnitions: []string{
+				"storage:\n  files:\n    - path: /etc/foo\n      contents:\n        inline: \"{{ .Hostname }}\"\n",
+				"storage:\n  files:\n    - path: /etc/bar\n      contents:\n        inline: bar\n",
+			},
+		})
+
+		Expect(files(ignition)).To(SatisfyAll(
+			HaveKeyWithValue("/etc/hostname", "machine-0\n"),
+			HaveKeyWithValue("/etc/foo", "{{ .Hostname }}"),
+			HaveKeyWithValue("/etc/bar", "bar"),
+		))
+		paths := []string{}
+		for _, file := range ignition["storage"].(map[string]any)["files"].([]any) {
+			paths = append(paths, file.(map[string]any)["path"].(string))
+		}
+		Expect(paths[len(paths)-2:]).To(Equal([]string{"/etc/foo", "/etc/bar"}))
+
+		By("failing if an additional ignition is not valid YAML")
+		_, err := Render(&Config{Hostname: "machine-0", AdditionalIgnitions: []string{"storage: [files"}})
+		Expect(err).To(MatchError(ContainSubstring("failed to parse additional ignition 0")))
+	})
+
+	It("should ignore cloud-config keys without Ignition equivalent", func() {
+		userData := "#cloud-config\nhostname: foo\npackages:\n  - vim\nruncmd:\n  - echo hello\n"
+		Expect(unsupportedCloudConfigKeys(map[string]any{"hostname": "foo", "packages": []any{"vim"}, "runcmd": []any{"echo hello"}})).To(Equal([]string{"hostname", "packages"}))
+
+		ignition := render(&Config{Hostname: "machine-0", UserData: userData})
+		Expect(files(ignition)).To(SatisfyAll(
+			HaveKeyWithValue("/etc/hostname", "machine-0\n"),
+			HaveKeyWithValue("/var/lib/metal-cloud-config/init.sh", "#!/bin/sh\nset -e\necho hello\n"),
+		))
+	})
+
+	It("should reject unsupported cloud-config file encodings", func() {
+		_, err := Render(&Config{Hostname: "machine-0", UserData: "#cloud-config\nwrite_files:\n  - path: /etc/foo\n    encoding: gzip\n    content: bar\n"})
+		Expect(err).To(MatchError(ContainSubstring(`encoding "gzip" of "/etc/foo" is not supported`)))
+	})
+})
+
+func render(config *Config) map[string]any {
+	GinkgoHelper()
+	ignition, err := Render(config)
+	Expect(err).NotTo(HaveOccurred())
+	return decodeJSON(ignition)
+}
+
+func decodeJSON(data string) map[string]any {
+	GinkgoHelper()
+	decoded := map[string]any{}
+	Expect(json.Unmarshal([]byte(data), &decoded)).To(Succeed())
+	return decoded
+}
+
+// files returns the decoded contents of the files in the ignition by path
+func files(ignition map[string]any) map[string]string {
+	GinkgoHelper()
+	contents := map[string]string{}
+	storage, _ := ignition["storage"].(map[string]any)
+	fileList, _ := storage["files"].([]any)
+	for _, f := range fileList {
+		file := f.(map[string]any)
+		source, _ := file["contents"].(map[string]any)["source"].(string)
+		contents[file["path"].(string)] = decodeDataURL(source)
+	}
+	return contents
+}
+
+// mergedConfigs returns the decoded configs which Ignition merges on boot
+func mergedConfigs(ignition map[string]any) []string {
+	GinkgoHelper()
+	var configs []string
+	config, _ := ignition["ignition"].(map[string]any)["config"].(map[string]any)
+	merge, _ := config["merge"].([]any)
+	for _, m := range merge {
+		configs = append(configs, decodeDataURL(m.(map[string]any)["source"].(string)))
+	}
+	return configs
+}
+
+func decodeDataURL(source string) string {
+	GinkgoHelper()
+	if data, ok := strings.CutPrefix(source, "data:;base64,"); ok {
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		Expect(err).NotTo(HaveOccurred())
+		return string(decoded)
+	}
+	data, ok := strings.CutPrefix(source, "data:,")
+	Expect(ok).To(BeTrue(), "unexpected data URL %q", source)
+	decoded, err := url.PathUnescape(data)
+	Expect(err).NotTo(HaveOccurred())
+	return decoded
+}
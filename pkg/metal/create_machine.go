@@ -6,18 +6,24 @@ package metal
 import (
 	"context"
 	"fmt"
+	"maps"
+	"time"
 
 	apiv1alpha1 "github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/v1alpha1"
 	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/validation"
 	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/cmd"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/metrics"
 
 	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
 
+	machinev1alpha1 "github.com/gardener/machine-controller-manager/pkg/apis/machine/v1alpha1"
 	"github.com/gardener/machine-controller-manager/pkg/util/provider/driver"
 	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/codes"
 	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/status"
+	"github.com/prometheus/client_golang/prometheus"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -41,26 +47,38 @@ func (d *metalDriver) CreateMachine(ctx context.Context, req *driver.CreateMachi
 		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to get provider spec: %v", err))
 	}
 
+	if serverName := getPinnedServerName(req.Machine, providerSpec); serverName != "" {
+		if err := d.ensureServerIsClaimable(ctx, serverName, req.Machine.Name); err != nil {
+			return nil, err
+		}
+	}
+
 	serverClaim, err := d.createServerClaim(ctx, req, providerSpec)
 	if err != nil {
 		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to create ServerClaim: %v", err))
 	}
 
-	// we need the server to be bound if not the ServerClaimName policy in order to get the node name
-	if d.nodeNamePolicy != cmd.NodeNamePolicyServerClaimName {
-		serverBound, err := d.ServerIsBound(ctx, serverClaim)
-		if err != nil {
-			return nil, status.Error(codes.Internal, fmt.Sprintf("failed to check if server is bound: %v", err))
-		}
+	if d.serverBindingTimedOut(serverClaim) {
+		return nil, d.releaseUnboundServerClaim(ctx, req.Machine, serverClaim)
+	}
 
-		if serverBound {
+	// we need the server to be bound if not the ServerClaimName policy in order to get the node name.
+	// The applied ServerClaim is checked, as a cached read might not observe a newly created ServerClaim yet.
+	if d.nodeNamePolicy != cmd.NodeNamePolicyServerClaimName {
+		if serverClaim.Spec.ServerRef != nil {
 			klog.V(3).InfoS("Server is already bound, removing recreate annotation", "name", serverClaim.Name, "namespace", serverClaim.Namespace)
+			d.recordEventf(req.Machine, serverClaim, corev1.EventTypeNormal, EventReasonServerBound, "ServerClaim is bound to Server %q", serverClaim.Spec.ServerRef.Name)
+			d.observeServerClaimBound(req.MachineClass.Name, req.Machine.Name, serverClaim, getMetricLabelsForProviderSpec(req.MachineClass, providerSpec))
 			err = d.patchServerClaimWithRecreateAnnotation(ctx, serverClaim, false)
 			if err != nil {
 				return nil, status.Error(codes.Internal, fmt.Sprintf("failed to patch ServerClaim without recreate annotation: %v", err))
 			}
 		} else {
 			klog.V(3).InfoS("Server is still not bound, adding recreate annotation", "name", serverClaim.Name, "namespace", serverClaim.Namespace)
+			d.recordEvent(req.Machine, serverClaim, corev1.EventTypeNormal, EventReasonWaitingForServerBinding, "Waiting for a Server matching the selector to be bound")
+			if serverClaim.Annotations[validation.AnnotationKeyMCMMachineRecreate] != "true" {
+				metrics.ServerClaimRecreateMarked.With(getMetricLabelsForProviderSpec(req.MachineClass, providerSpec)).Inc()
+			}
 			err = d.patchServerClaimWithRecreateAnnotation(ctx, serverClaim, true)
 			if err != nil {
 				return nil, status.Error(codes.Internal, fmt.Sprintf("failed to patch ServerClaim with recreate annotation: %v", err))
@@ -90,6 +108,46 @@ func isEmptyCreateRequest(req *driver.CreateMachineRequest) bool {
 	return req == nil || req.MachineClass == nil || req.Machine == nil || req.Secret == nil
 }
 
+// getPinnedServerName returns the name of the Server the Machine is pinned to, or an empty string if the Server
+// should be selected by labels. The Machine annotation takes precedence over the mapping in the ProviderSpec.
+func getPinnedServerName(machine *machinev1alpha1.Machine, providerSpec *apiv1alpha1.ProviderSpec) string {
+	if serverName := machine.Annotations[apiv1alpha1.ServerNameAnnotation]; serverName != "" {
+		return serverName
+	}
+	return providerSpec.ServerNames[machine.Name]
+}
+
+// ensureServerIsClaimable checks if the pinned Server exists and is not claimed by another ServerClaim,
+// since the ServerClaim of the Machine would never be bound otherwise
+func (d *metalDriver) ensureServerIsClaimable(ctx context.Context, serverName, machineName string) error {
+	server := &metalv1alpha1.Server{}
+	if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
+		return metalClient.Get(ctx, client.ObjectKey{Name: serverName}, server)
+	}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return status.Error(codes.NotFound, fmt.Sprintf("pinned server %q not found", serverName))
+		}
+		return status.Error(codes.Internal, fmt.Sprintf("failed to get pinned server %q: %v", serverName, err))
+	}
+
+	if claimRef := server.Spec.ServerClaimRef; claimRef != nil && (claimRef.Name != machineName || claimRef.Namespace != d.metalNamespace) {
+		return status.Error(codes.FailedPrecondition, fmt.Sprintf("pinned server %q is already claimed by ServerClaim %q in namespace %q", serverName, claimRef.Name, claimRef.Namespace))
+	}
+
+	return nil
+}
+
+// getServerClaimLabels returns the labels of the provider spec and the label marking the ServerClaim as managed by
+// the driver
+func getServerClaimLabels(providerSpec *apiv1alpha1.ProviderSpec) map[string]string {
+	labels := maps.Clone(providerSpec.Labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[validation.LabelKeyManagedBy] = validation.LabelValueManagedBy
+	return labels
+}
+
 // createServerClaim creates and applies a ServerClaim object with proper ignition data
 func (d *metalDriver) createServerClaim(ctx context.Context, req *driver.CreateMachineRequest, providerSpec *apiv1alpha1.ProviderSpec) (*metalv1alpha1.ServerClaim, error) {
 	klog.V(3).InfoS("Creating ServerClaim", "name", req.Machine.Name, "namespace", d.metalNamespace)
@@ -102,18 +160,21 @@ func (d *metalDriver) createServerClaim(ctx context.Context, req *driver.CreateM
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      req.Machine.Name,
 			Namespace: d.metalNamespace,
-			Labels:    providerSpec.Labels,
+			Labels:    getServerClaimLabels(providerSpec),
 		},
 		Spec: metalv1alpha1.ServerClaimSpec{
-			Power: metalv1alpha1.PowerOff, // we will power on the server later
-			ServerSelector: &metav1.LabelSelector{
-				MatchLabels:      providerSpec.ServerLabels,
-				MatchExpressions: nil,
-			},
-			Image: providerSpec.Image,
+			Power:          metalv1alpha1.PowerOff, // we will power on the server later
+			ServerSelector: providerSpec.ServerSelector(),
+			Image:          providerSpec.Image,
 		},
 	}
 
+	if serverName := getPinnedServerName(req.Machine, providerSpec); serverName != "" {
+		klog.V(3).InfoS("Pinning ServerClaim to Server", "name", serverClaim.Name, "namespace", serverClaim.Namespace, "server", serverName)
+		serverClaim.Spec.ServerRef = &corev1.LocalObjectReference{Name: serverName}
+		serverClaim.Spec.ServerSelector = nil
+	}
+
 	if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
 		return metalClient.Patch(ctx, serverClaim, client.Apply, fieldOwner, client.ForceOwnership) //nolint:staticcheck // SA1019: Client.Apply() requires ApplyConfiguration types not yet provided by metal-operator
 	}); err != nil {
@@ -121,6 +182,7 @@ func (d *metalDriver) createServerClaim(ctx context.Context, req *driver.CreateM
 	}
 
 	klog.V(3).InfoS("Successfully created ServerClaim", "name", serverClaim.Name, "namespace", serverClaim.Namespace)
+	d.recordEventf(req.Machine, serverClaim, corev1.EventTypeNormal, EventReasonServerClaimCreated, "Created ServerClaim for Machine %q", req.Machine.Name)
 	return serverClaim, nil
 }
 
@@ -146,15 +208,45 @@ func (d *metalDriver) patchServerClaimWithRecreateAnnotation(ctx context.Context
 	return nil
 }
 
-// ServerIsBound checks if the server is already bound
-func (d *metalDriver) ServerIsBound(ctx context.Context, serverClaim *metalv1alpha1.ServerClaim) (bool, error) {
+// serverBindingTimedOut returns true if the ServerClaim is still not bound after the binding timeout
+func (d *metalDriver) serverBindingTimedOut(serverClaim *metalv1alpha1.ServerClaim) bool {
+	return serverClaimIsUnbound(serverClaim) && d.serverBindingTimeout > 0 && time.Since(serverClaim.CreationTimestamp.Time) > d.serverBindingTimeout
+}
+
+// serverClaimIsUnbound returns true if no Server has been bound to the ServerClaim yet. The ServerRef of a ServerClaim
+// pinned to a Server is set by the driver, it is bound once the metal-operator reports its phase as bound.
+func serverClaimIsUnbound(serverClaim *metalv1alpha1.ServerClaim) bool {
+	if serverClaim.Spec.ServerSelector == nil && serverClaim.Spec.ServerRef != nil {
+		return serverClaim.Status.Phase != metalv1alpha1.PhaseBound
+	}
+	return serverClaim.Spec.ServerRef == nil
+}
+
+// releaseUnboundServerClaim deletes a ServerClaim which was not bound within the binding timeout and returns
+// a codes.ResourceExhausted error, so that running out of matching Servers is reported instead of waiting forever
+func (d *metalDriver) releaseUnboundServerClaim(ctx context.Context, machine *machinev1alpha1.Machine, serverClaim *metalv1alpha1.ServerClaim) error {
+	// a pinned ServerClaim has no selector, the Server it is pinned to is reported instead
+	wanted := fmt.Sprintf("matching the selector %q", metav1.FormatLabelSelector(serverClaim.Spec.ServerSelector))
+	if serverClaim.Spec.ServerSelector == nil && serverClaim.Spec.ServerRef != nil {
+		wanted = fmt.Sprintf("named %q", serverClaim.Spec.ServerRef.Name)
+	}
+	klog.V(3).InfoS("Server was not bound within the binding timeout, deleting ServerClaim", "name", serverClaim.Name, "namespace", serverClaim.Namespace, "server", wanted, "timeout", d.serverBindingTimeout)
+	d.recordEventf(machine, serverClaim, corev1.EventTypeWarning, EventReasonServerBindingTimedOut, "No Server %s was bound within %s", wanted, d.serverBindingTimeout)
+
 	if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
-		return metalClient.Get(ctx, client.ObjectKeyFromObject(serverClaim), serverClaim)
-	}); err != nil {
-		return false, fmt.Errorf("failed to get ServerClaim %q: %v", serverClaim.Name, err)
+		return metalClient.Delete(ctx, serverClaim)
+	}); client.IgnoreNotFound(err) != nil {
+		return status.Error(codes.Internal, fmt.Sprintf("failed to delete unbound ServerClaim: %v", err))
 	}
 
-	return serverClaim.Spec.ServerRef != nil, nil
+	return status.Error(codes.ResourceExhausted, fmt.Sprintf("no server %s was bound to ServerClaim %q in namespace %q within %s", wanted, serverClaim.Name, serverClaim.Namespace, d.serverBindingTimeout))
+}
+
+// observeServerClaimBound records the binding duration of the ServerClaim once per Machine
+func (d *metalDriver) observeServerClaimBound(machineClassName, machineName string, serverClaim *metalv1alpha1.ServerClaim, labels prometheus.Labels) {
+	if d.metricsTracker.firstObservation(machineClassName, machineName, "ServerClaimBound") {
+		metrics.ServerClaimBindingDuration.With(labels).Observe(time.Since(serverClaim.CreationTimestamp.Time).Seconds())
+	}
 }
 
 func (d *metalDriver) nodeExistsByName(ctx context.Context, nodeName string) bool {
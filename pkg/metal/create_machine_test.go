@@ -5,6 +5,8 @@ package metal
 
 import (
 	"fmt"
+	"maps"
+	"time"
 
 	"github.com/gardener/machine-controller-manager/pkg/util/provider/driver"
 	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/codes"
@@ -19,6 +21,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	. "sigs.k8s.io/controller-runtime/pkg/envtest/komega"
 )
@@ -72,8 +75,9 @@ var _ = Describe("CreateMachine", func() {
 
 		Eventually(Object(serverClaim)).Should(SatisfyAll(
 			HaveField("ObjectMeta.Labels", map[string]string{
-				ShootNameLabelKey:      "my-shoot",
-				ShootNamespaceLabelKey: "my-shoot-namespace",
+				ShootNameLabelKey:            "my-shoot",
+				ShootNamespaceLabelKey:       "my-shoot-namespace",
+				validation.LabelKeyManagedBy: validation.LabelValueManagedBy,
 			}),
 			HaveField("Spec.Power", metalv1alpha1.PowerOff),
 			HaveField("Spec.ServerSelector", &metav1.LabelSelector{
@@ -84,13 +88,142 @@ var _ = Describe("CreateMachine", func() {
 		))
 
 		By("ensuring the cleanup of the machine")
-		DeferCleanup((*drv).DeleteMachine, &driver.DeleteMachineRequest{
+		DeferCleanup(deleteMachine, *drv, &driver.DeleteMachineRequest{
 			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
 			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
 			Secret:       providerSecret,
 		})
 	})
 
+	It("should pass the server match expressions to the ServerClaim", func(ctx SpecContext) {
+		machineIndex := 2
+		machineName := fmt.Sprintf("%s-%d", machineNamePrefix, machineIndex)
+
+		providerSpec := maps.Clone(testing.SampleProviderSpec)
+		providerSpec["serverMatchExpressions"] = []metav1.LabelSelectorRequirement{
+			{Key: "memory-class", Operator: metav1.LabelSelectorOpIn, Values: []string{"256Gi", "512Gi"}},
+			{Key: "rack", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"r12"}},
+		}
+
+		By("creating machine")
+		Expect((*drv).CreateMachine(ctx, &driver.CreateMachineRequest{
+			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
+			MachineClass: newMachineClass(v1alpha1.ProviderName, providerSpec),
+			Secret:       providerSecret,
+		})).Error().NotTo(HaveOccurred())
+
+		By("ensuring that the ServerClaim selects servers by labels and match expressions")
+		serverClaim := &metalv1alpha1.ServerClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      machineName,
+				Namespace: ns.Name,
+			},
+		}
+
+		Eventually(Object(serverClaim)).Should(HaveField("Spec.ServerSelector", &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				"instance-type": "bar",
+			},
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "memory-class", Operator: metav1.LabelSelectorOpIn, Values: []string{"256Gi", "512Gi"}},
+				{Key: "rack", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"r12"}},
+			},
+		}))
+
+		By("ensuring the cleanup of the machine")
+		DeferCleanup(deleteMachine, *drv, &driver.DeleteMachineRequest{
+			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
+			MachineClass: newMachineClass(v1alpha1.ProviderName, providerSpec),
+			Secret:       providerSecret,
+		})
+	})
+
+	It("should pin the ServerClaim to the Server named in the Machine annotation", func(ctx SpecContext) {
+		machineIndex := 6
+		machineName := fmt.Sprintf("%s-%d", machineNamePrefix, machineIndex)
+
+		By("creating a server")
+		server := &metalv1alpha1.Server{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-server-pinned",
+			},
+			Spec: metalv1alpha1.ServerSpec{
+				SystemUUID: "12345",
+			},
+		}
+		Expect(k8sClient.Create(ctx, server)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, server)
+
+		By("creating machine")
+		machine := newMachine(ns, machineNamePrefix, machineIndex, nil)
+		machine.Annotations = map[string]string{v1alpha1.ServerNameAnnotation: server.Name}
+		Expect((*drv).CreateMachine(ctx, &driver.CreateMachineRequest{
+			Machine:      machine,
+			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
+			Secret:       providerSecret,
+		})).Error().NotTo(HaveOccurred())
+
+		By("ensuring that the ServerClaim references the Server")
+		serverClaim := &metalv1alpha1.ServerClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      machineName,
+				Namespace: ns.Name,
+			},
+		}
+		Eventually(Object(serverClaim)).Should(SatisfyAll(
+			HaveField("Spec.ServerRef", &corev1.LocalObjectReference{Name: server.Name}),
+			HaveField("Spec.ServerSelector", BeNil()),
+		))
+
+		By("ensuring the cleanup of the machine")
+		DeferCleanup(deleteMachine, *drv, &driver.DeleteMachineRequest{
+			Machine:      machine,
+			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
+			Secret:       providerSecret,
+		})
+	})
+
+	It("should fail if the pinned Server is claimed by another ServerClaim", func(ctx SpecContext) {
+		machineIndex := 7
+		machineName := fmt.Sprintf("%s-%d", machineNamePrefix, machineIndex)
+
+		By("creating a server claimed by another ServerClaim")
+		server := &metalv1alpha1.Server{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-server-claimed",
+			},
+			Spec: metalv1alpha1.ServerSpec{
+				SystemUUID: "12345",
+				ServerClaimRef: &metalv1alpha1.ImmutableObjectReference{
+					Namespace: ns.Name,
+					Name:      "other-claim",
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, server)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, server)
+
+		By("failing to create the machine pinned via the ProviderSpec")
+		providerSpec := maps.Clone(testing.SampleProviderSpec)
+		providerSpec["serverNames"] = map[string]string{machineName: server.Name}
+		createMachineResponse, err := (*drv).CreateMachine(ctx, &driver.CreateMachineRequest{
+			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
+			MachineClass: newMachineClass(v1alpha1.ProviderName, providerSpec),
+			Secret:       providerSecret,
+		})
+		Expect(err).Should(MatchError(status.Error(codes.FailedPrecondition, fmt.Sprintf("pinned server %q is already claimed by ServerClaim %q in namespace %q", server.Name, "other-claim", ns.Name))))
+		Expect(createMachineResponse).To(BeNil())
+
+		By("ensuring that no ServerClaim has been created")
+		serverClaim := &metalv1alpha1.ServerClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      machineName,
+				Namespace: ns.Name,
+			},
+		}
+		Consistently(Get(serverClaim)).Should(Satisfy(apierrors.IsNotFound))
+	})
+
 	It("should fail if the machine request is empty", func(ctx SpecContext) {
 		By("failing if the machine request is empty")
 		createMachineResponse, err := (*drv).CreateMachine(ctx, nil)
@@ -132,6 +265,70 @@ var _ = Describe("CreateMachine", func() {
 		Expect(err).Should(MatchError(status.Error(codes.Internal, `failed to get provider spec: failed to validate provider spec and secret: [userData: Required value: userData is required]`)))
 		Expect(createMachineResponse).To(BeNil())
 	})
+
+	It("should release the ServerClaim if no server is bound within the binding timeout", func(ctx SpecContext) {
+		machineIndex := 9
+		machineName := fmt.Sprintf("%s-%d", machineNamePrefix, machineIndex)
+		(*drv).(*metalDriver).serverBindingTimeout = time.Nanosecond
+
+		By("failing to create the machine once the binding timeout has passed")
+		Eventually(func() error {
+			_, err := (*drv).CreateMachine(ctx, &driver.CreateMachineRequest{
+				Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
+				MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
+				Secret:       providerSecret,
+			})
+			return err
+		}).Should(MatchError(status.Error(codes.ResourceExhausted, fmt.Sprintf(`no server matching the selector %q was bound to ServerClaim %q in namespace %q within %s`, "instance-type=bar", machineName, ns.Name, time.Nanosecond))))
+
+		By("ensuring that the unbound ServerClaim has been deleted")
+		serverClaim := &metalv1alpha1.ServerClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      machineName,
+				Namespace: ns.Name,
+			},
+		}
+		Eventually(Get(serverClaim)).Should(Satisfy(apierrors.IsNotFound))
+	})
+
+	It("should release the ServerClaim if the pinned server is not bound within the binding timeout", func(ctx SpecContext) {
+		machineIndex := 10
+		machineName := fmt.Sprintf("%s-%d", machineNamePrefix, machineIndex)
+		(*drv).(*metalDriver).serverBindingTimeout = time.Nanosecond
+
+		By("creating a server")
+		server := &metalv1alpha1.Server{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-server-pinned-timeout",
+			},
+			Spec: metalv1alpha1.ServerSpec{
+				SystemUUID: "12345",
+			},
+		}
+		Expect(k8sClient.Create(ctx, server)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, server)
+
+		By("failing to create the machine once the binding timeout has passed")
+		machine := newMachine(ns, machineNamePrefix, machineIndex, nil)
+		machine.Annotations = map[string]string{v1alpha1.ServerNameAnnotation: server.Name}
+		Eventually(func() error {
+			_, err := (*drv).CreateMachine(ctx, &driver.CreateMachineRequest{
+				Machine:      machine,
+				MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
+				Secret:       providerSecret,
+			})
+			return err
+		}).Should(MatchError(status.Error(codes.ResourceExhausted, fmt.Sprintf(`no server named %q was bound to ServerClaim %q in namespace %q within %s`, server.Name, machineName, ns.Name, time.Nanosecond))))
+
+		By("ensuring that the unbound ServerClaim has been deleted")
+		serverClaim := &metalv1alpha1.ServerClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      machineName,
+				Namespace: ns.Name,
+			},
+		}
+		Eventually(Get(serverClaim)).Should(Satisfy(apierrors.IsNotFound))
+	})
 })
 
 var _ = Describe("CreateMachine with Server name as hostname", func() {
@@ -191,8 +388,9 @@ var _ = Describe("CreateMachine with Server name as hostname", func() {
 
 		Eventually(Object(serverClaim)).Should(SatisfyAll(
 			HaveField("ObjectMeta.Labels", map[string]string{
-				ShootNameLabelKey:      "my-shoot",
-				ShootNamespaceLabelKey: "my-shoot-namespace",
+				ShootNameLabelKey:            "my-shoot",
+				ShootNamespaceLabelKey:       "my-shoot-namespace",
+				validation.LabelKeyManagedBy: validation.LabelValueManagedBy,
 			}),
 			HaveField("Spec.Power", metalv1alpha1.PowerOff),
 			HaveField("Spec.ServerSelector", &metav1.LabelSelector{
@@ -203,7 +401,7 @@ var _ = Describe("CreateMachine with Server name as hostname", func() {
 		))
 
 		By("ensuring the cleanup of the machine")
-		DeferCleanup((*drv).DeleteMachine, &driver.DeleteMachineRequest{
+		DeferCleanup(deleteMachine, *drv, &driver.DeleteMachineRequest{
 			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
 			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
 			Secret:       providerSecret,
@@ -245,6 +443,12 @@ var _ = Describe("CreateMachine with Server name as hostname", func() {
 
 		Eventually(Object(serverClaim)).Should(HaveField("ObjectMeta.Annotations", HaveKeyWithValue(validation.AnnotationKeyMCMMachineRecreate, "true")))
 
+		By("ensuring that the ServerClaim creation and the pending binding have been recorded as events")
+		for _, events := range []chan string{recorder.Events, machineRecorder.Events} {
+			Eventually(events).Should(Receive(Equal(fmt.Sprintf("Normal %s Created ServerClaim for Machine %q", EventReasonServerClaimCreated, machineName))))
+			Eventually(events).Should(Receive(Equal(fmt.Sprintf("Normal %s Waiting for a Server matching the selector to be bound", EventReasonWaitingForServerBinding))))
+		}
+
 		By("starting a non-blocking goroutine to patch ServerClaim")
 		go func() {
 			defer GinkgoRecover()
@@ -275,12 +479,37 @@ var _ = Describe("CreateMachine with Server name as hostname", func() {
 		Eventually(Object(serverClaim)).ShouldNot(HaveField("ObjectMeta.Annotations", HaveKey(validation.AnnotationKeyMCMMachineRecreate)))
 
 		By("ensuring the cleanup of the machine")
-		DeferCleanup((*drv).DeleteMachine, &driver.DeleteMachineRequest{
+		DeferCleanup(deleteMachine, *drv, &driver.DeleteMachineRequest{
 			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
 			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
 			Secret:       providerSecret,
 		})
 	})
+
+	It("should release the ServerClaim if no server is bound within the binding timeout", func(ctx SpecContext) {
+		machineIndex := 8
+		machineName := fmt.Sprintf("%s-%d", machineNamePrefix, machineIndex)
+		(*drv).(*metalDriver).serverBindingTimeout = time.Nanosecond
+
+		By("failing to create the machine once the binding timeout has passed")
+		Eventually(func() error {
+			_, err := (*drv).CreateMachine(ctx, &driver.CreateMachineRequest{
+				Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
+				MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
+				Secret:       providerSecret,
+			})
+			return err
+		}).Should(MatchError(status.Error(codes.ResourceExhausted, fmt.Sprintf(`no server matching the selector %q was bound to ServerClaim %q in namespace %q within %s`, "instance-type=bar", machineName, ns.Name, time.Nanosecond))))
+
+		By("ensuring that the unbound ServerClaim has been deleted")
+		serverClaim := &metalv1alpha1.ServerClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      machineName,
+				Namespace: ns.Name,
+			},
+		}
+		Eventually(Get(serverClaim)).Should(Satisfy(apierrors.IsNotFound))
+	})
 })
 
 var _ = Describe("CreateMachine using BMC names", func() {
@@ -370,7 +599,7 @@ var _ = Describe("CreateMachine using BMC names", func() {
 		Eventually(Object(serverClaim)).ShouldNot(HaveField("ObjectMeta.Annotations", HaveKey(validation.AnnotationKeyMCMMachineRecreate)))
 
 		By("ensuring the cleanup of the machine")
-		DeferCleanup((*drv).DeleteMachine, &driver.DeleteMachineRequest{
+		DeferCleanup(deleteMachine, *drv, &driver.DeleteMachineRequest{
 			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
 			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
 			Secret:       providerSecret,
@@ -12,16 +12,18 @@ import (
 	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/codes"
 	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/status"
 	apiv1alpha1 "github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/v1alpha1"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/validation"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/metrics"
 	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
+	capiv1beta1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-// DeleteMachine handles a machine deletion request and also deletes ignitionSecret associated with it
+// DeleteMachine handles a machine deletion request and also deletes the ignitionSecret and IPAddressClaims associated with it
 func (d *metalDriver) DeleteMachine(ctx context.Context, req *driver.DeleteMachineRequest) (*driver.DeleteMachineResponse, error) {
 	if isEmptyDeleteRequest(req) {
 		return nil, status.Error(codes.InvalidArgument, "received empty DeleteMachineRequest")
@@ -34,6 +36,8 @@ func (d *metalDriver) DeleteMachine(ctx context.Context, req *driver.DeleteMachi
 	klog.V(3).Infof("Machine deletion request has been received for %q", req.Machine.Name)
 	defer klog.V(3).Infof("Machine deletion request has been processed for %q", req.Machine.Name)
 
+	deletionStarted := d.metricsTracker.deletionStartTime(req.MachineClass.Name, req.Machine.Name)
+
 	ignitionSecret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      d.getIgnitionNameForMachine(ctx, req.Machine.Name),
@@ -48,6 +52,15 @@ func (d *metalDriver) DeleteMachine(ctx context.Context, req *driver.DeleteMachi
 		return nil, status.Error(codes.Unknown, fmt.Sprintf("error deleting ignition secret: %s", err.Error()))
 	}
 
+	if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
+		return metalClient.DeleteAllOf(ctx, &corev1.Secret{}, client.InNamespace(d.metalNamespace), client.MatchingLabels{
+			validation.LabelKeyIgnitionSecretName: ignitionSecret.Name,
+		})
+	}); err != nil {
+		// Unknown leads to short retry in machine controller
+		return nil, status.Error(codes.Unknown, fmt.Sprintf("error deleting ignition parts: %s", err.Error()))
+	}
+
 	serverClaim := &metalv1alpha1.ServerClaim{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      req.Machine.Name,
@@ -57,38 +70,72 @@ func (d *metalDriver) DeleteMachine(ctx context.Context, req *driver.DeleteMachi
 
 	if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
 		return metalClient.Delete(ctx, serverClaim)
-	}); err != nil {
-		if !apierrors.IsNotFound(err) {
-			// Unknown leads to short retry in machine controller
-			return nil, status.Error(codes.Unknown, fmt.Sprintf("error deleting pod: %s", err.Error()))
-		}
-		return nil, status.Error(codes.NotFound, err.Error())
+	}); client.IgnoreNotFound(err) != nil {
+		// Unknown leads to short retry in machine controller
+		return nil, status.Error(codes.Unknown, fmt.Sprintf("error deleting ServerClaim: %s", err.Error()))
 	}
 
-	// Actively wait until the server claim is deleted since the extension contract in machine-controller-manager expects drivers to
-	// do so. If we would not wait until the server claim is gone it might happen that the kubelet could re-register the Node
-	// object even after it was already deleted by machine-controller-manager.
-	if err := wait.PollUntilContextTimeout(ctx, 5*time.Second, 10*time.Minute, true, func(ctx context.Context) (bool, error) {
-		if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
-			return metalClient.Get(ctx, client.ObjectKeyFromObject(serverClaim), serverClaim)
-		}); err != nil {
-			if apierrors.IsNotFound(err) {
-				return true, nil
-			}
-			// Unknown leads to short retry in machine controller
-			return false, status.Error(codes.Unknown, err.Error())
-		}
-		return false, nil
+	// The IPAddressClaims are deleted explicitly instead of relying on the garbage collection via their owner
+	// reference to the ServerClaim, so that their addresses are released before the Machine is reported as deleted.
+	if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
+		return metalClient.DeleteAllOf(ctx, &capiv1beta1.IPAddressClaim{}, client.InNamespace(d.metalNamespace), client.MatchingLabels{
+			validation.LabelKeyServerClaimName:      serverClaim.Name,
+			validation.LabelKeyServerClaimNamespace: serverClaim.Namespace,
+		})
 	}); err != nil {
-		klog.V(3).Infof("Failed to wait for ServerClaim deletion: %v", err)
-		// will be retried with short retry by machine controller
-		return nil, status.Error(codes.DeadlineExceeded, err.Error())
+		// Unknown leads to short retry in machine controller
+		return nil, status.Error(codes.Unknown, fmt.Sprintf("error deleting IPAddressClaims: %s", err.Error()))
+	}
+
+	// The extension contract in machine-controller-manager expects drivers to only report a successful deletion once the
+	// server claim is gone. If we would not wait until the server claim is gone it might happen that the kubelet could
+	// re-register the Node object even after it was already deleted by machine-controller-manager. Instead of blocking
+	// the worker we return a retryable status code as long as the deletion is still in progress.
+	deletionInProgress, err := d.isDeletionInProgress(ctx, serverClaim)
+	if err != nil {
+		// Unknown leads to short retry in machine controller
+		return nil, status.Error(codes.Unknown, err.Error())
+	}
+	if deletionInProgress != "" {
+		klog.V(3).Infof("Deletion of machine %q is still in progress: %s", req.Machine.Name, deletionInProgress)
+		d.recordEvent(req.Machine, serverClaim, corev1.EventTypeNormal, EventReasonDeletionWaiting, deletionInProgress)
+		// MCM provider retry with codes.Unavailable will ensure a short retry in 5 seconds
+		return nil, status.Error(codes.Unavailable, deletionInProgress)
 	}
 
 	klog.V(3).Infof("ServerClaim %q in namespace %q has been deleted", serverClaim.Name, serverClaim.Namespace)
+	d.recordEventf(req.Machine, serverClaim, corev1.EventTypeNormal, EventReasonDeleted, "ServerClaim of Machine %q has been deleted", req.Machine.Name)
+	metrics.DeleteMachineWaitDuration.With(getMetricLabels(req.MachineClass)).Observe(time.Since(deletionStarted).Seconds())
+	d.metricsTracker.forget(req.Machine.Name)
 	return &driver.DeleteMachineResponse{}, nil
 }
 
+// isDeletionInProgress returns a reason if the ServerClaim or any of its IPAddressClaims still exist
+func (d *metalDriver) isDeletionInProgress(ctx context.Context, serverClaim *metalv1alpha1.ServerClaim) (string, error) {
+	if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
+		return metalClient.Get(ctx, client.ObjectKeyFromObject(serverClaim), serverClaim)
+	}); err == nil {
+		return fmt.Sprintf("ServerClaim %q in namespace %q is still being deleted", serverClaim.Name, serverClaim.Namespace), nil
+	} else if !apierrors.IsNotFound(err) {
+		return "", fmt.Errorf("failed to get ServerClaim %q: %w", client.ObjectKeyFromObject(serverClaim), err)
+	}
+
+	ipClaimList := &capiv1beta1.IPAddressClaimList{}
+	if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
+		return metalClient.List(ctx, ipClaimList, client.InNamespace(d.metalNamespace), client.MatchingLabels{
+			validation.LabelKeyServerClaimName:      serverClaim.Name,
+			validation.LabelKeyServerClaimNamespace: serverClaim.Namespace,
+		})
+	}); err != nil {
+		return "", fmt.Errorf("failed to list IPAddressClaims for ServerClaim %q: %w", client.ObjectKeyFromObject(serverClaim), err)
+	}
+	if len(ipClaimList.Items) > 0 {
+		return fmt.Sprintf("%d IPAddressClaims of ServerClaim %q in namespace %q are still being deleted", len(ipClaimList.Items), serverClaim.Name, serverClaim.Namespace), nil
+	}
+
+	return "", nil
+}
+
 func isEmptyDeleteRequest(req *driver.DeleteMachineRequest) bool {
 	return req == nil || req.MachineClass == nil || req.Machine == nil || req.Secret == nil
 }
@@ -7,7 +7,10 @@ import (
 	"fmt"
 
 	"github.com/gardener/machine-controller-manager/pkg/util/provider/driver"
+	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/codes"
+	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/status"
 	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/v1alpha1"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/validation"
 	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/cmd"
 	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/metal/testing"
 	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
@@ -16,6 +19,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	capiv1beta1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
 	. "sigs.k8s.io/controller-runtime/pkg/envtest/komega"
 )
 
@@ -133,4 +138,75 @@ var _ = Describe("DeleteMachine", func() {
 		By("waiting for the ignition secret to be gone")
 		Eventually(Get(ignition)).Should(Satisfy(apierrors.IsNotFound))
 	})
+
+	It("should return a retryable error as long as the ServerClaim and IPAddressClaims still exist", func(ctx SpecContext) {
+		machineIndex := 3
+		machineName := fmt.Sprintf("%s-%d", machineNamePrefix, machineIndex)
+
+		By("creating a ServerClaim with a finalizer")
+		serverClaim := &metalv1alpha1.ServerClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:  ns.Name,
+				Name:       machineName,
+				Finalizers: []string{"metal.ironcore.dev/test"},
+			},
+			Spec: metalv1alpha1.ServerClaimSpec{
+				Power: metalv1alpha1.PowerOff,
+			},
+		}
+		Expect(k8sClient.Create(ctx, serverClaim)).To(Succeed())
+
+		By("creating an IPAddressClaim with a finalizer for the ServerClaim")
+		ipClaim := &capiv1beta1.IPAddressClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:  ns.Name,
+				Name:       fmt.Sprintf("%s-pxe", machineName),
+				Finalizers: []string{"metal.ironcore.dev/test"},
+				Labels: map[string]string{
+					validation.LabelKeyServerClaimName:      machineName,
+					validation.LabelKeyServerClaimNamespace: ns.Name,
+				},
+			},
+			Spec: capiv1beta1.IPAddressClaimSpec{
+				PoolRef: corev1.TypedLocalObjectReference{
+					APIGroup: ptr.To("ipam.cluster.x-k8s.io"),
+					Kind:     "GlobalInClusterIPPool",
+					Name:     "pool",
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, ipClaim)).To(Succeed())
+
+		deleteRequest := &driver.DeleteMachineRequest{
+			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
+			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
+			Secret:       providerSecret,
+		}
+
+		By("ensuring that the deletion is reported as in progress while the ServerClaim exists")
+		_, err := (*drv).DeleteMachine(ctx, deleteRequest)
+		Expect(err).To(MatchError(status.Error(codes.Unavailable, fmt.Sprintf("ServerClaim %q in namespace %q is still being deleted", machineName, ns.Name))))
+
+		By("removing the finalizer of the ServerClaim")
+		Eventually(Update(serverClaim, func() {
+			serverClaim.Finalizers = nil
+		})).Should(Succeed())
+		Eventually(Get(serverClaim)).Should(Satisfy(apierrors.IsNotFound))
+
+		By("ensuring that the deletion is reported as in progress while the IPAddressClaim exists")
+		_, err = (*drv).DeleteMachine(ctx, deleteRequest)
+		Expect(err).To(MatchError(status.Error(codes.Unavailable, fmt.Sprintf("1 IPAddressClaims of ServerClaim %q in namespace %q are still being deleted", machineName, ns.Name))))
+
+		By("ensuring that the IPAddressClaim is deleted explicitly")
+		Eventually(Object(ipClaim)).Should(HaveField("DeletionTimestamp", Not(BeNil())))
+
+		By("removing the finalizer of the IPAddressClaim")
+		Eventually(Update(ipClaim, func() {
+			ipClaim.Finalizers = nil
+		})).Should(Succeed())
+		Eventually(Get(ipClaim)).Should(Satisfy(apierrors.IsNotFound))
+
+		By("ensuring that the machine deletion succeeds once everything is gone")
+		Expect((*drv).DeleteMachine(ctx, deleteRequest)).To(Equal(&driver.DeleteMachineResponse{}))
+	})
 })
@@ -5,9 +5,12 @@ package metal
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	apiv1alpha1 "github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/v1alpha1"
 	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/validation"
@@ -17,15 +20,15 @@ import (
 
 	machinev1alpha1 "github.com/gardener/machine-controller-manager/pkg/apis/machine/v1alpha1"
 	"github.com/gardener/machine-controller-manager/pkg/util/provider/driver"
-	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/codes"
-	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/status"
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilvalidation "k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
+	capiv1beta1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -33,6 +36,9 @@ const (
 	defaultIgnitionKey     = "ignition"
 	ShootNameLabelKey      = "shoot-name"
 	ShootNamespaceLabelKey = "shoot-namespace"
+
+	// ipAddressClaimNameHashLength is the length of the hash suffix of shortened IPAddressClaim names
+	ipAddressClaimNameHashLength = 10
 )
 
 var (
@@ -44,23 +50,73 @@ type metalDriver struct {
 	clientProvider *mcmclient.Provider
 	metalNamespace string
 	nodeNamePolicy cmd.NodeNamePolicy
+	csiDriverNames []string
+	// serverBindingTimeout is the time after the creation of a ServerClaim after which an unbound ServerClaim is
+	// released, a zero value disables the timeout
+	serverBindingTimeout time.Duration
+	ignitionSizeOptions  IgnitionSizeOptions
+	// recorder emits events on ServerClaims in the metal cluster
+	recorder record.EventRecorder
+	// machineRecorder emits events on Machines in the control cluster
+	machineRecorder record.EventRecorder
+	metricsTracker  *machineMetricsTracker
+}
+
+// IgnitionSizeOptions limit the size of the ignition Secrets created by the driver
+type IgnitionSizeOptions struct {
+	// CompressionThreshold is the size of a rendered ignition above which its file contents are compressed,
+	// a zero value disables the compression
+	CompressionThreshold int
+	// MaxSize is the maximum size of an ignition, larger ignitions are split into a pointer config and parts
+	// which are stored in separate Secrets, a zero value disables the splitting
+	MaxSize int
+	// PartBaseURL is the URL the parts of a split ignition are served from as <PartBaseURL>/<namespace>/<name>.
+	// The parts are stored in Secrets and can be served by the IgnitionPartHandler. Ignitions exceeding the maximum
+	// size are not split but fail to render if it is not set.
+	PartBaseURL string
 }
 
-func (d *metalDriver) GetVolumeIDs(_ context.Context, _ *driver.GetVolumeIDsRequest) (*driver.GetVolumeIDsResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "Metal Provider does not yet implement GetVolumeIDs")
+// DriverOptions configure the metal driver
+type DriverOptions struct {
+	// NodeNamePolicy defaults to cmd.NodeNamePolicyServerClaimName
+	NodeNamePolicy cmd.NodeNamePolicy
+	// CSIDriverNames are the CSI drivers whose volumes are reported by GetVolumeIDs in addition to
+	// DefaultCSIDriverNames
+	CSIDriverNames []string
+	// ServerBindingTimeout is the time after the creation of a ServerClaim after which an unbound ServerClaim is
+	// released, a zero value disables the timeout
+	ServerBindingTimeout time.Duration
+	IgnitionSizeOptions  IgnitionSizeOptions
+	// Recorder emits the lifecycle events on the ServerClaims in the metal cluster, events are dropped if it is not set
+	Recorder record.EventRecorder
+	// MachineRecorder emits the lifecycle events on the Machines in the control cluster, events are dropped if it is
+	// not set
+	MachineRecorder record.EventRecorder
 }
 
 // NewDriver returns a new Gardener metal driver object
-func NewDriver(clientProvider *mcmclient.Provider, namespace string, nodeNamePolicy cmd.NodeNamePolicy) driver.Driver {
-	return &metalDriver{
-		clientProvider: clientProvider,
-		metalNamespace: namespace,
-		nodeNamePolicy: nodeNamePolicy,
+func NewDriver(clientProvider *mcmclient.Provider, namespace string, options DriverOptions) driver.Driver {
+	if options.NodeNamePolicy == "" {
+		options.NodeNamePolicy = cmd.NodeNamePolicyServerClaimName
+	}
+	if options.Recorder == nil {
+		options.Recorder = &record.FakeRecorder{}
+	}
+	if options.MachineRecorder == nil {
+		options.MachineRecorder = &record.FakeRecorder{}
 	}
-}
 
-func (d *metalDriver) GenerateMachineClassForMigration(_ context.Context, _ *driver.GenerateMachineClassForMigrationRequest) (*driver.GenerateMachineClassForMigrationResponse, error) {
-	return &driver.GenerateMachineClassForMigrationResponse{}, nil
+	return &metalDriver{
+		clientProvider:       clientProvider,
+		metalNamespace:       namespace,
+		nodeNamePolicy:       options.NodeNamePolicy,
+		csiDriverNames:       options.CSIDriverNames,
+		serverBindingTimeout: options.ServerBindingTimeout,
+		ignitionSizeOptions:  options.IgnitionSizeOptions,
+		recorder:             options.Recorder,
+		machineRecorder:      options.MachineRecorder,
+		metricsTracker:       newMachineMetricsTracker(),
+	}
 }
 
 func (d *metalDriver) getIgnitionNameForMachine(ctx context.Context, machineName string) string {
@@ -105,26 +161,132 @@ func getNodeName(ctx context.Context, policy cmd.NodeNamePolicy, serverClaim *me
 	return "", fmt.Errorf("unknown node name policy: %s", policy)
 }
 
+// ipAddressClaimRef is an IPAddressClaim of an IPAMConfig and the IP family the allocated address must have
+type ipAddressClaimRef struct {
+	name string
+	// legacyName is the name the IPAddressClaim had before long names were shortened with a hash suffix
+	legacyName string
+	ipamRef    *apiv1alpha1.IPAMObjectReference
+	// family is empty if the address of a single-stack network may have any family
+	family corev1.IPFamily
+}
+
+// getIPAddressClaimRefs returns the IPAddressClaims of an IPAMConfig, which are two for a dual-stack network
+// with an IPv4 and an IPv6 address
+func getIPAddressClaimRefs(machineName string, ipamConfig apiv1alpha1.IPAMConfig) []ipAddressClaimRef {
+	if ipamConfig.IPv6IPAMRef == nil {
+		return []ipAddressClaimRef{{
+			name:       getIPAddressClaimName(machineName, ipamConfig.MetadataKey),
+			legacyName: getLegacyIPAddressClaimName(machineName, ipamConfig.MetadataKey),
+			ipamRef:    ipamConfig.IPAMRef,
+		}}
+	}
+
+	return []ipAddressClaimRef{
+		{
+			name:       getIPAddressClaimName(machineName, ipamConfig.MetadataKey),
+			legacyName: getLegacyIPAddressClaimName(machineName, ipamConfig.MetadataKey),
+			ipamRef:    ipamConfig.IPAMRef,
+			family:     corev1.IPv4Protocol,
+		},
+		{
+			name:       getIPAddressClaimName(machineName, ipamConfig.MetadataKey+"-ipv6"),
+			legacyName: getLegacyIPAddressClaimName(machineName, ipamConfig.MetadataKey+"-ipv6"),
+			ipamRef:    ipamConfig.IPv6IPAMRef,
+			family:     corev1.IPv6Protocol,
+		},
+	}
+}
+
+// getIPAddressClaimName returns the name of the IPAddressClaim of a metadata key. Names exceeding the maximum length
+// are shortened and suffixed with a hash of the full name, so that long metadata keys with a common prefix do not
+// share an IPAddressClaim.
 func getIPAddressClaimName(machineName, metadataKey string) string {
+	ipAddrClaimName := fmt.Sprintf("%s-%s", machineName, metadataKey)
+	if len(ipAddrClaimName) <= utilvalidation.DNS1123SubdomainMaxLength {
+		return ipAddrClaimName
+	}
+
+	hash := sha256.Sum256([]byte(ipAddrClaimName))
+	suffix := hex.EncodeToString(hash[:])[:ipAddressClaimNameHashLength]
+	prefix := strings.TrimRight(ipAddrClaimName[:utilvalidation.DNS1123SubdomainMaxLength-len(suffix)-1], "-.")
+	return fmt.Sprintf("%s-%s", prefix, suffix)
+}
+
+// getLegacyIPAddressClaimName returns the name of the IPAddressClaim of a metadata key as it was created by earlier
+// versions of the driver, which cut long names at the maximum length
+func getLegacyIPAddressClaimName(machineName, metadataKey string) string {
 	ipAddrClaimName := fmt.Sprintf("%s-%s", machineName, metadataKey)
 	if len(ipAddrClaimName) > utilvalidation.DNS1123SubdomainMaxLength {
-		klog.InfoS("IPAddressClaim name is too long, it will be shortened which can cause name collisions", "name", ipAddrClaimName)
 		ipAddrClaimName = ipAddrClaimName[:utilvalidation.DNS1123SubdomainMaxLength]
 	}
 	return ipAddrClaimName
 }
 
+// getIPAddressClaimNames returns the names of the IPAddressClaims of the machine by the name of the claim refs.
+// IPAddressClaims created under their legacy name keep this name, they are looked up by the ServerClaim name label.
+// Legacy names shared by several claim refs are ambiguous and not migrated.
+func (d *metalDriver) getIPAddressClaimNames(ctx context.Context, machineName string, ipamConfigs []apiv1alpha1.IPAMConfig) (map[string]string, error) {
+	names := map[string]string{}
+	legacyNameCount := map[string]int{}
+	for _, ipamConfig := range ipamConfigs {
+		for _, claimRef := range getIPAddressClaimRefs(machineName, ipamConfig) {
+			names[claimRef.name] = claimRef.name
+			if claimRef.legacyName != claimRef.name {
+				legacyNameCount[claimRef.legacyName]++
+			}
+		}
+	}
+	if len(legacyNameCount) == 0 {
+		return names, nil
+	}
+
+	ipClaimList := &capiv1beta1.IPAddressClaimList{}
+	if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
+		return metalClient.List(ctx, ipClaimList, client.InNamespace(d.metalNamespace), client.MatchingLabels{
+			validation.LabelKeyServerClaimName:      machineName,
+			validation.LabelKeyServerClaimNamespace: d.metalNamespace,
+		})
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list IPAddressClaims of ServerClaim %q: %w", machineName, err)
+	}
+	existing := map[string]struct{}{}
+	for _, ipClaim := range ipClaimList.Items {
+		existing[ipClaim.Name] = struct{}{}
+	}
+
+	for _, ipamConfig := range ipamConfigs {
+		for _, claimRef := range getIPAddressClaimRefs(machineName, ipamConfig) {
+			if legacyNameCount[claimRef.legacyName] != 1 {
+				continue
+			}
+			if _, ok := existing[claimRef.name]; ok {
+				continue
+			}
+			if _, ok := existing[claimRef.legacyName]; ok {
+				klog.V(3).InfoS("Using IPAddressClaim with legacy name", "name", claimRef.legacyName, "namespace", d.metalNamespace, "newName", claimRef.name)
+				names[claimRef.name] = claimRef.legacyName
+			}
+		}
+	}
+	return names, nil
+}
+
 func GetProviderSpec(machineClass *machinev1alpha1.MachineClass, secret *corev1.Secret) (*apiv1alpha1.ProviderSpec, error) {
 	if machineClass == nil {
 		return nil, errors.New("MachineClass is not set in request")
 	}
 
-	var providerSpec *apiv1alpha1.ProviderSpec
-	if err := json.Unmarshal(machineClass.ProviderSpec.Raw, &providerSpec); err != nil {
+	providerSpec, warnings, decodingErr, err := decodeProviderSpec(machineClass, field.NewPath("providerSpec"))
+	if err != nil {
 		return nil, err
 	}
+	for _, warning := range warnings {
+		klog.InfoS("Provider spec of MachineClass has a deprecated or unknown field", "machineClass", machineClass.Name, "warning", warning)
+	}
 
-	validationErr := validation.ValidateProviderSpecAndSecret(providerSpec, secret, field.NewPath("providerSpec"))
+	validationErr := decodingErr
+	validationErr = append(validationErr, validation.ValidateProviderSpecAndSecret(providerSpec, secret, field.NewPath("providerSpec"))...)
 	if validationErr.ToAggregate() != nil && len(validationErr.ToAggregate().Errors()) > 0 {
 		return nil, fmt.Errorf("failed to validate provider spec and secret: %v", validationErr.ToAggregate().Errors())
 	}
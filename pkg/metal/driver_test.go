@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metal
+
+import (
+	"strings"
+
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/v1alpha1"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/validation"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/cmd"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilvalidation "k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/ptr"
+	capiv1beta1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
+)
+
+var _ = Describe("IPAddressClaim names", func() {
+	ns, _, drv := SetupTest(cmd.NodeNamePolicyServerClaimName)
+
+	longKey := strings.Repeat("a", utilvalidation.DNS1123SubdomainMaxLength)
+
+	It("should keep names within the maximum length unchanged", func() {
+		Expect(getIPAddressClaimName("machine-0", "pxe")).To(Equal("machine-0-pxe"))
+	})
+
+	It("should shorten long names with a hash suffix", func() {
+		name := getIPAddressClaimName("machine-0", longKey+"-b")
+		Expect(name).To(HaveLen(utilvalidation.DNS1123SubdomainMaxLength))
+		Expect(utilvalidation.IsDNS1123Subdomain(name)).To(BeEmpty())
+		Expect(getIPAddressClaimName("machine-0", longKey+"-b")).To(Equal(name))
+
+		By("ensuring that long metadata keys with a common prefix get different names")
+		Expect(getIPAddressClaimName("machine-0", longKey+"-c")).NotTo(Equal(name))
+		Expect(getLegacyIPAddressClaimName("machine-0", longKey+"-c")).To(Equal(getLegacyIPAddressClaimName("machine-0", longKey+"-b")))
+	})
+
+	It("should keep using IPAddressClaims created under their legacy name", func(ctx SpecContext) {
+		machineName := "machine-legacy"
+		ipamRef := &v1alpha1.IPAMObjectReference{
+			APIGroup: "ipam.cluster.x-k8s.io",
+			Kind:     "GlobalInClusterIPPool",
+			Name:     "pool",
+		}
+		ipamConfigs := []v1alpha1.IPAMConfig{
+			{MetadataKey: longKey, IPAMRef: ipamRef},
+			{MetadataKey: "pxe", IPAMRef: ipamRef},
+		}
+
+		By("creating an IPAddressClaim under the legacy name")
+		legacyName := getLegacyIPAddressClaimName(machineName, longKey)
+		ipClaim := &capiv1beta1.IPAddressClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: ns.Name,
+				Name:      legacyName,
+				Labels: map[string]string{
+					validation.LabelKeyServerClaimName:      machineName,
+					validation.LabelKeyServerClaimNamespace: ns.Name,
+				},
+			},
+			Spec: capiv1beta1.IPAddressClaimSpec{
+				PoolRef: corev1.TypedLocalObjectReference{
+					APIGroup: ptr.To(ipamRef.APIGroup),
+					Kind:     ipamRef.Kind,
+					Name:     ipamRef.Name,
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, ipClaim)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ipClaim)
+
+		By("ensuring that the legacy name is used")
+		names, err := (*drv).(*metalDriver).getIPAddressClaimNames(ctx, machineName, ipamConfigs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names).To(Equal(map[string]string{
+			getIPAddressClaimName(machineName, longKey): legacyName,
+			"machine-legacy-pxe":                        "machine-legacy-pxe",
+		}))
+
+		By("ensuring that ambiguous legacy names are not used")
+		names, err = (*drv).(*metalDriver).getIPAddressClaimNames(ctx, machineName, append(ipamConfigs, v1alpha1.IPAMConfig{MetadataKey: longKey + "-b", IPAMRef: ipamRef}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names).To(HaveKeyWithValue(getIPAddressClaimName(machineName, longKey), getIPAddressClaimName(machineName, longKey)))
+	})
+})
@@ -0,0 +1,38 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metal
+
+import (
+	machinev1alpha1 "github.com/gardener/machine-controller-manager/pkg/apis/machine/v1alpha1"
+	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
+)
+
+// Reasons of the events which are emitted on Machines and their ServerClaims during the lifecycle of a Machine
+const (
+	EventReasonServerClaimCreated      = "ServerClaimCreated"
+	EventReasonWaitingForServerBinding = "WaitingForServerBinding"
+	EventReasonServerBound             = "ServerBound"
+	EventReasonServerBindingTimedOut   = "ServerBindingTimedOut"
+	EventReasonIPAddressAllocated      = "IPAddressAllocated"
+	EventReasonIPAddressClaimsInvalid  = "IPAddressClaimsInvalid"
+	EventReasonIgnitionRendered        = "IgnitionRendered"
+	EventReasonIgnitionRenderFailed    = "IgnitionRenderFailed"
+	EventReasonNetworkNotConfigured    = "NetworkNotConfigured"
+	EventReasonInvalidLoopbackAddress  = "InvalidLoopbackAddress"
+	EventReasonPoweredOn               = "PoweredOn"
+	EventReasonDeletionWaiting         = "DeletionWaiting"
+	EventReasonDeleted                 = "Deleted"
+)
+
+// recordEvent emits a lifecycle event on the ServerClaim in the metal cluster and on the Machine in the control cluster
+func (d *metalDriver) recordEvent(machine *machinev1alpha1.Machine, serverClaim *metalv1alpha1.ServerClaim, eventType, reason, message string) {
+	d.recorder.Event(serverClaim, eventType, reason, message)
+	d.machineRecorder.Event(machine, eventType, reason, message)
+}
+
+// recordEventf is like recordEvent, but formats the message with fmt.Sprintf
+func (d *metalDriver) recordEventf(machine *machinev1alpha1.Machine, serverClaim *metalv1alpha1.ServerClaim, eventType, reason, messageFmt string, args ...any) {
+	d.recorder.Eventf(serverClaim, eventType, reason, messageFmt, args...)
+	d.machineRecorder.Eventf(machine, eventType, reason, messageFmt, args...)
+}
@@ -0,0 +1,219 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	machinev1alpha1 "github.com/gardener/machine-controller-manager/pkg/apis/machine/v1alpha1"
+	"github.com/gardener/machine-controller-manager/pkg/util/provider/driver"
+	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/codes"
+	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/status"
+	apiv1alpha1 "github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// legacyProviderSpecRenames maps field names of legacy provider specs to their current names
+	legacyProviderSpecRenames = map[string]string{
+		"metaData": "metadata",
+	}
+	// legacyProviderSpecDrops are fields of legacy provider specs which have no counterpart anymore
+	legacyProviderSpecDrops = []string{
+		"machineClassName",
+		"machinePoolName",
+	}
+)
+
+// legacyIgnitionSecretField references a Secret in the metal namespace containing the ignition in legacy provider
+// specs, it is migrated to an ignition reference
+const legacyIgnitionSecretField = "ignitionSecret"
+
+// GenerateMachineClassForMigration translates a legacy metal provider spec into the current ProviderSpec format
+func (d *metalDriver) GenerateMachineClassForMigration(_ context.Context, req *driver.GenerateMachineClassForMigrationRequest) (*driver.GenerateMachineClassForMigrationResponse, error) {
+	if req == nil || req.MachineClass == nil {
+		return nil, status.Error(codes.InvalidArgument, "received empty GenerateMachineClassForMigrationRequest")
+	}
+
+	klog.V(3).Infof("Migrate request has been received for %q", req.MachineClass.Name)
+	defer klog.V(3).Infof("Migrate request has been processed for %q", req.MachineClass.Name)
+
+	legacySpec, err := getLegacyProviderSpec(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("failed to read legacy provider spec: %v", err))
+	}
+
+	providerSpec, changes, unknownFields, err := migrateProviderSpec(legacySpec)
+	if err != nil {
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to migrate provider spec: %v", err))
+	}
+
+	for _, change := range changes {
+		klog.InfoS("Migrated legacy provider spec field", "machineClass", req.MachineClass.Name, "change", change)
+	}
+	if len(unknownFields) > 0 {
+		klog.Warningf("Dropped unknown fields %v of the legacy provider spec of MachineClass %q, their configuration is lost", unknownFields, req.MachineClass.Name)
+	}
+
+	providerSpecJSON, err := json.Marshal(providerSpec)
+	if err != nil {
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to marshal provider spec: %v", err))
+	}
+
+	req.MachineClass.ProviderSpec = runtime.RawExtension{Raw: providerSpecJSON}
+	req.MachineClass.Provider = apiv1alpha1.ProviderName
+
+	return &driver.GenerateMachineClassForMigrationResponse{}, nil
+}
+
+// getLegacyProviderSpec returns the legacy provider spec as a generic map, either from the provider specific
+// machine class or from the MachineClass itself
+func getLegacyProviderSpec(req *driver.GenerateMachineClassForMigrationRequest) (map[string]any, error) {
+	var raw []byte
+
+	switch class := req.ProviderSpecificMachineClass.(type) {
+	case nil:
+		raw = req.MachineClass.ProviderSpec.Raw
+	case *machinev1alpha1.MachineClass:
+		raw = class.ProviderSpec.Raw
+	case *unstructured.Unstructured:
+		spec, ok := class.Object["spec"].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("machine class %q has no spec", class.GetName())
+		}
+		return spec, nil
+	case map[string]any:
+		return class, nil
+	case []byte:
+		raw = class
+	default:
+		return nil, fmt.Errorf("unsupported provider specific machine class type %T", class)
+	}
+
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("provider spec is empty")
+	}
+
+	legacySpec := map[string]any{}
+	if err := json.Unmarshal(raw, &legacySpec); err != nil {
+		return nil, err
+	}
+	return legacySpec, nil
+}
+
+// migrateProviderSpec converts a legacy provider spec into the current ProviderSpec and reports every
+// field which was renamed, migrated or dropped on the way. Unknown fields are dropped and returned separately.
+func migrateProviderSpec(legacySpec map[string]any) (*apiv1alpha1.ProviderSpec, []string, []string, error) {
+	knownFields := providerSpecFieldNames()
+	migratedSpec := make(map[string]any, len(legacySpec))
+	var (
+		changes       []string
+		unknownFields []string
+		ignitionRef   *apiv1alpha1.IgnitionReference
+	)
+
+	keys := make([]string, 0, len(legacySpec))
+	for key := range legacySpec {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		value := legacySpec[key]
+		if key == legacyIgnitionSecretField {
+			ref, err := migrateLegacyIgnitionSecret(value, legacySpec["ignitionSecretKey"])
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			ignitionRef = ref
+			changes = append(changes, fmt.Sprintf("field %q migrated to %q", key, "ignitionRefs"))
+			continue
+		}
+		if newKey, ok := legacyProviderSpecRenames[key]; ok {
+			if _, exists := legacySpec[newKey]; exists {
+				changes = append(changes, fmt.Sprintf("field %q dropped in favor of %q", key, newKey))
+				continue
+			}
+			migratedSpec[newKey] = value
+			changes = append(changes, fmt.Sprintf("field %q renamed to %q", key, newKey))
+			continue
+		}
+		if slices.Contains(legacyProviderSpecDrops, key) {
+			changes = append(changes, fmt.Sprintf("field %q dropped", key))
+			continue
+		}
+		if !slices.Contains(knownFields, key) {
+			unknownFields = append(unknownFields, key)
+			continue
+		}
+		migratedSpec[key] = value
+	}
+
+	migratedSpecJSON, err := json.Marshal(migratedSpec)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	providerSpec := &apiv1alpha1.ProviderSpec{}
+	if err := json.Unmarshal(migratedSpecJSON, providerSpec); err != nil {
+		return nil, nil, nil, err
+	}
+	if ignitionRef != nil {
+		providerSpec.IgnitionRefs = append(providerSpec.IgnitionRefs, *ignitionRef)
+	}
+
+	return providerSpec, changes, unknownFields, nil
+}
+
+// migrateLegacyIgnitionSecret converts the legacy reference to an ignition Secret into an ignition reference. Like
+// in legacy provider specs, the ignition is read from the key set by ignitionSecretKey or the default key.
+func migrateLegacyIgnitionSecret(value, keyValue any) (*apiv1alpha1.IgnitionReference, error) {
+	secretRef, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("field %q must be an object, got %T", legacyIgnitionSecretField, value)
+	}
+	name, _ := secretRef["name"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("field %q has no name", legacyIgnitionSecretField)
+	}
+
+	key := defaultIgnitionKey
+	if keyValue != nil {
+		secretKey, ok := keyValue.(string)
+		if !ok {
+			return nil, fmt.Errorf("field %q must be a string, got %T", "ignitionSecretKey", keyValue)
+		}
+		if secretKey != "" {
+			key = secretKey
+		}
+	}
+
+	return &apiv1alpha1.IgnitionReference{
+		SecretRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: name},
+			Key:                  key,
+		},
+	}, nil
+}
+
+// providerSpecFieldNames returns the JSON field names of the current ProviderSpec
+func providerSpecFieldNames() []string {
+	var names []string
+	providerSpecType := reflect.TypeFor[apiv1alpha1.ProviderSpec]()
+	for i := range providerSpecType.NumField() {
+		name, _, _ := strings.Cut(providerSpecType.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
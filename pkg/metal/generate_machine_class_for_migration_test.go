@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metal
+
+import (
+	"encoding/json"
+	"net/netip"
+
+	gardenermachinev1alpha1 "github.com/gardener/machine-controller-manager/pkg/apis/machine/v1alpha1"
+	"github.com/gardener/machine-controller-manager/pkg/util/provider/driver"
+	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/codes"
+	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/status"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/v1alpha1"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/metal/testing"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("GenerateMachineClassForMigration", func() {
+	drv := NewDriver(nil, "", DriverOptions{})
+
+	It("should fail on an empty request", func(ctx SpecContext) {
+		_, err := drv.GenerateMachineClassForMigration(ctx, nil)
+		Expect(err).To(MatchError(status.Error(codes.InvalidArgument, "received empty GenerateMachineClassForMigrationRequest")))
+	})
+
+	It("should translate a legacy provider spec into the current format", func(ctx SpecContext) {
+		legacyMachineClass := newMachineClass("", testing.SampleProviderSpec)
+		machineClass := &gardenermachinev1alpha1.MachineClass{}
+
+		_, err := drv.GenerateMachineClassForMigration(ctx, &driver.GenerateMachineClassForMigrationRequest{
+			ProviderSpecificMachineClass: legacyMachineClass,
+			MachineClass:                 machineClass,
+			ClassSpec:                    &gardenermachinev1alpha1.ClassSpec{},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(machineClass.Provider).To(Equal(v1alpha1.ProviderName))
+
+		providerSpec := &v1alpha1.ProviderSpec{}
+		Expect(json.Unmarshal(machineClass.ProviderSpec.Raw, providerSpec)).To(Succeed())
+		Expect(providerSpec.Image).To(Equal("my-image"))
+		Expect(providerSpec.IgnitionSecretKey).To(Equal("ignition"))
+		Expect(providerSpec.IgnitionRefs).To(Equal([]v1alpha1.IgnitionReference{
+			{SecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "foo"}, Key: "ignition"}},
+		}))
+		Expect(providerSpec.ServerLabels).To(Equal(map[string]string{"instance-type": "bar"}))
+		Expect(providerSpec.Metadata).To(Equal(map[string]any{"foo": "bar", "baz": "100"}))
+		Expect(providerSpec.DnsServers).To(Equal([]netip.Addr{netip.MustParseAddr("1.2.3.4"), netip.MustParseAddr("5.6.7.8")}))
+	})
+
+	It("should report renamed, migrated, dropped and unknown fields", func() {
+		providerSpec, changes, unknownFields, err := migrateProviderSpec(map[string]any{
+			"image":            "my-image",
+			"metaData":         map[string]any{"foo": "bar"},
+			"ignitionSecret":   map[string]any{"name": "foo"},
+			"machineClassName": "foo",
+			"unknownField":     "value",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(providerSpec.Metadata).To(Equal(map[string]any{"foo": "bar"}))
+		Expect(changes).To(ConsistOf(
+			`field "ignitionSecret" migrated to "ignitionRefs"`,
+			`field "machineClassName" dropped`,
+			`field "metaData" renamed to "metadata"`,
+		))
+		Expect(unknownFields).To(ConsistOf("unknownField"))
+	})
+
+	It("should migrate the legacy ignition Secret to an ignition reference", func() {
+		providerSpec, _, _, err := migrateProviderSpec(map[string]any{
+			"ignitionSecret":    map[string]any{"name": "foo"},
+			"ignitionSecretKey": "custom",
+			"ignitionRefs":      []any{map[string]any{"providerSecretKey": "bar"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(providerSpec.IgnitionRefs).To(Equal([]v1alpha1.IgnitionReference{
+			{ProviderSecretKey: "bar"},
+			{SecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "foo"}, Key: "custom"}},
+		}))
+
+		By("defaulting the key of the ignition Secret")
+		providerSpec, _, _, err = migrateProviderSpec(map[string]any{
+			"ignitionSecret": map[string]any{"name": "foo"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(providerSpec.IgnitionRefs).To(Equal([]v1alpha1.IgnitionReference{
+			{SecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "foo"}, Key: "ignition"}},
+		}))
+
+		By("failing if the ignition Secret has no name")
+		_, _, _, err = migrateProviderSpec(map[string]any{
+			"ignitionSecret": map[string]any{},
+		})
+		Expect(err).To(MatchError(`field "ignitionSecret" has no name`))
+	})
+
+	It("should prefer the current field name if both are set", func() {
+		providerSpec, changes, _, err := migrateProviderSpec(map[string]any{
+			"metaData": map[string]any{"old": "value"},
+			"metadata": map[string]any{"new": "value"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(providerSpec.Metadata).To(Equal(map[string]any{"new": "value"}))
+		Expect(changes).To(ConsistOf(`field "metaData" dropped in favor of "metadata"`))
+	})
+})
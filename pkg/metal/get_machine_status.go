@@ -13,6 +13,7 @@ import (
 	apiv1alpha1 "github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/v1alpha1"
 	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/validation"
 	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
@@ -67,6 +68,7 @@ func (d *metalDriver) GetMachineStatus(ctx context.Context, req *driver.GetMachi
 
 	if err := d.validateIPAddressClaims(ctx, req, serverClaim, providerSpec); err != nil {
 		klog.V(3).Infof("Machine initialization flow will be retriggered, IPAddressClaims validation was unsuccessful: %q", req.Machine.Name)
+		d.recordEventf(req.Machine, serverClaim, corev1.EventTypeWarning, EventReasonIPAddressClaimsInvalid, "IPAddressClaims are invalid, Machine will be reinitialized: %v", err)
 		// MCM provider retry with codes.Uninitialized which triggers machine initialization flow (requires valid GetMachineStatusResponse)
 		return getMachineStatusResponse, status.Error(codes.Uninitialized, fmt.Sprintf("unsuccessful IPAddressClaims validation, will reinitialize: %v", err))
 	}
@@ -87,31 +89,38 @@ func isEmptyMachineStatusRequest(req *driver.GetMachineStatusRequest) bool {
 func (d *metalDriver) validateIPAddressClaims(ctx context.Context, req *driver.GetMachineStatusRequest, serverClaim *metalv1alpha1.ServerClaim, providerSpec *apiv1alpha1.ProviderSpec) error {
 	klog.V(3).InfoS("Validating IPAddressClaims", "name", req.Machine.Name, "namespace", d.metalNamespace)
 
+	ipClaimNames, err := d.getIPAddressClaimNames(ctx, req.Machine.Name, providerSpec.IPAMConfig)
+	if err != nil {
+		return err
+	}
+
 	for _, ipamConfig := range providerSpec.IPAMConfig {
 		if ipamConfig.IPAMRef == nil {
 			return fmt.Errorf("IPAMRef of an IPAMConfig %q is not set", ipamConfig.MetadataKey)
 		}
 
-		ipClaim := &capiv1beta1.IPAddressClaim{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      getIPAddressClaimName(req.Machine.Name, ipamConfig.MetadataKey),
-				Namespace: d.metalNamespace,
-			},
-		}
-
-		if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
-			return metalClient.Get(ctx, client.ObjectKeyFromObject(ipClaim), ipClaim)
-		}); err != nil {
-			return fmt.Errorf("failed to get IPAddressClaim %q: %v", ipClaim.Name, err)
-		}
-
-		validationErr := validation.ValidateIPAddressClaim(ipClaim, serverClaim, req.Machine.Name, d.metalNamespace)
-		if validationErr.ToAggregate() != nil && len(validationErr.ToAggregate().Errors()) > 0 {
-			return fmt.Errorf("failed to validate IPAddressClaim %s/%s: %v", ipClaim.Namespace, ipClaim.Name, validationErr.ToAggregate().Errors())
-		}
-
-		if ipClaim.Status.AddressRef.Name == "" {
-			return fmt.Errorf("IPAddressClaim %s/%s still not bound", ipClaim.Namespace, ipClaim.Name)
+		for _, claimRef := range getIPAddressClaimRefs(req.Machine.Name, ipamConfig) {
+			ipClaim := &capiv1beta1.IPAddressClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      ipClaimNames[claimRef.name],
+					Namespace: d.metalNamespace,
+				},
+			}
+
+			if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
+				return metalClient.Get(ctx, client.ObjectKeyFromObject(ipClaim), ipClaim)
+			}); err != nil {
+				return fmt.Errorf("failed to get IPAddressClaim %q: %v", ipClaim.Name, err)
+			}
+
+			validationErr := validation.ValidateIPAddressClaim(ipClaim, serverClaim, req.Machine.Name, d.metalNamespace)
+			if validationErr.ToAggregate() != nil && len(validationErr.ToAggregate().Errors()) > 0 {
+				return fmt.Errorf("failed to validate IPAddressClaim %s/%s: %v", ipClaim.Namespace, ipClaim.Name, validationErr.ToAggregate().Errors())
+			}
+
+			if ipClaim.Status.AddressRef.Name == "" {
+				return fmt.Errorf("IPAddressClaim %s/%s still not bound", ipClaim.Namespace, ipClaim.Name)
+			}
 		}
 	}
 
@@ -107,7 +107,7 @@ var _ = Describe("GetMachineStatus", func() {
 		Expect(err).ToNot(HaveOccurred())
 
 		By("ensuring the cleanup of the machine")
-		DeferCleanup((*drv).DeleteMachine, &driver.DeleteMachineRequest{
+		DeferCleanup(deleteMachine, *drv, &driver.DeleteMachineRequest{
 			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
 			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
 			Secret:       providerSecret,
@@ -178,7 +178,7 @@ var _ = Describe("GetMachineStatus", func() {
 		Expect(err).ToNot(HaveOccurred())
 
 		By("ensuring the cleanup of the machine")
-		DeferCleanup((*drv).DeleteMachine, &driver.DeleteMachineRequest{
+		DeferCleanup(deleteMachine, *drv, &driver.DeleteMachineRequest{
 			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
 			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
 			Secret:       providerSecret,
@@ -236,7 +236,7 @@ var _ = Describe("GetMachineStatus", func() {
 		Expect(err).Should(MatchError(status.Error(codes.NotFound, fmt.Sprintf("server claim %q is marked for recreation", machineName))))
 
 		By("ensuring the cleanup of the machine")
-		DeferCleanup((*drv).DeleteMachine, &driver.DeleteMachineRequest{
+		DeferCleanup(deleteMachine, *drv, &driver.DeleteMachineRequest{
 			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
 			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
 			Secret:       providerSecret,
@@ -328,7 +328,7 @@ var _ = Describe("GetMachineStatus", func() {
 		Expect(err).Should(MatchError(status.Error(codes.Uninitialized, fmt.Sprintf("unsuccessful IPAddressClaims validation, will reinitialize: failed to validate IPAddressClaim %s/%s-%s: [metadata.ownerReferences: Required value: IPAddressClaim must have an owner reference]", ns.Name, machineName, poolName))))
 
 		By("ensuring the cleanup of the machine")
-		DeferCleanup((*drv).DeleteMachine, &driver.DeleteMachineRequest{
+		DeferCleanup(deleteMachine, *drv, &driver.DeleteMachineRequest{
 			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
 			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
 			Secret:       providerSecret,
@@ -374,7 +374,7 @@ var _ = Describe("GetMachineStatus", func() {
 		Expect(err).Should(MatchError(status.Error(codes.Uninitialized, fmt.Sprintf("server claim %q is still not powered on, will reinitialize", machineName))))
 
 		By("ensuring the cleanup of the machine")
-		DeferCleanup((*drv).DeleteMachine, &driver.DeleteMachineRequest{
+		DeferCleanup(deleteMachine, *drv, &driver.DeleteMachineRequest{
 			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
 			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
 			Secret:       providerSecret,
@@ -456,7 +456,7 @@ var _ = Describe("GetMachineStatus using Server names", func() {
 		Expect(getMachineStatusResponse.NodeName).To(Equal(server.Name))
 
 		By("ensuring the cleanup of the machine")
-		DeferCleanup((*drv).DeleteMachine, &driver.DeleteMachineRequest{
+		DeferCleanup(deleteMachine, *drv, &driver.DeleteMachineRequest{
 			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
 			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
 			Secret:       providerSecret,
@@ -556,7 +556,7 @@ var _ = Describe("GetMachineStatus using BMC names", func() {
 		Expect(getMachineStatusResponse.NodeName).To(Equal(bmc.Name))
 
 		By("ensuring the cleanup of the machine")
-		DeferCleanup((*drv).DeleteMachine, &driver.DeleteMachineRequest{
+		DeferCleanup(deleteMachine, *drv, &driver.DeleteMachineRequest{
 			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
 			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
 			Secret:       providerSecret,
@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metal
+
+import (
+	"context"
+	"slices"
+
+	"github.com/gardener/machine-controller-manager/pkg/util/provider/driver"
+	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/codes"
+	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/status"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// DefaultCSIDriverNames are the CSI drivers commonly used on bare metal workers whose volumes are always considered
+var DefaultCSIDriverNames = []string{
+	"rook-ceph.rbd.csi.ceph.com",
+	"rbd.csi.ceph.com",
+	"topolvm.io",
+	"local.csi.openebs.io",
+}
+
+// GetVolumeIDs returns a list of volume IDs for the PersistentVolume specs which are backed by the metal workers
+func (d *metalDriver) GetVolumeIDs(_ context.Context, req *driver.GetVolumeIDsRequest) (*driver.GetVolumeIDsResponse, error) {
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "received empty GetVolumeIDsRequest")
+	}
+
+	klog.V(3).Infof("Get VolumeIDs request has been received for %d PV specs", len(req.PVSpecs))
+	defer klog.V(3).Infof("Get VolumeIDs request has been processed for %d PV specs", len(req.PVSpecs))
+
+	var volumeIDs []string
+	for _, pvSpec := range req.PVSpecs {
+		if volumeID := d.getVolumeID(pvSpec); volumeID != "" {
+			volumeIDs = append(volumeIDs, volumeID)
+		}
+	}
+
+	return &driver.GetVolumeIDsResponse{VolumeIDs: volumeIDs}, nil
+}
+
+// getVolumeID maps a PersistentVolume spec to a stable volume ID or returns an empty string if the volume is not handled.
+// Local volumes are identified by the hostname of their node affinity and their path, as the path alone is not unique across nodes.
+func (d *metalDriver) getVolumeID(pvSpec *corev1.PersistentVolumeSpec) string {
+	if pvSpec == nil {
+		return ""
+	}
+
+	switch {
+	case pvSpec.Local != nil:
+		if hostname := getLocalVolumeHostname(pvSpec.NodeAffinity); hostname != "" {
+			return hostname + ":" + pvSpec.Local.Path
+		}
+	case pvSpec.CSI != nil:
+		if slices.Contains(DefaultCSIDriverNames, pvSpec.CSI.Driver) || slices.Contains(d.csiDriverNames, pvSpec.CSI.Driver) {
+			return pvSpec.CSI.VolumeHandle
+		}
+	}
+
+	return ""
+}
+
+// getLocalVolumeHostname returns the hostname a local volume is bound to by its node affinity or an empty string
+func getLocalVolumeHostname(nodeAffinity *corev1.VolumeNodeAffinity) string {
+	if nodeAffinity == nil || nodeAffinity.Required == nil {
+		return ""
+	}
+
+	for _, term := range nodeAffinity.Required.NodeSelectorTerms {
+		for _, expression := range term.MatchExpressions {
+			if expression.Key == corev1.LabelHostname && expression.Operator == corev1.NodeSelectorOpIn && len(expression.Values) == 1 {
+				return expression.Values[0]
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metal
+
+import (
+	"github.com/gardener/machine-controller-manager/pkg/util/provider/driver"
+	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/codes"
+	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/status"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("GetVolumeIDs", func() {
+	drv := NewDriver(nil, "", DriverOptions{CSIDriverNames: []string{"custom.csi.example.com"}})
+
+	It("should fail on an empty request", func(ctx SpecContext) {
+		_, err := drv.GetVolumeIDs(ctx, nil)
+		Expect(err).To(MatchError(status.Error(codes.InvalidArgument, "received empty GetVolumeIDsRequest")))
+	})
+
+	It("should return the volume IDs of local and known CSI volumes", func(ctx SpecContext) {
+		response, err := drv.GetVolumeIDs(ctx, &driver.GetVolumeIDsRequest{
+			PVSpecs: []*corev1.PersistentVolumeSpec{
+				{
+					PersistentVolumeSource: corev1.PersistentVolumeSource{
+						Local: &corev1.LocalVolumeSource{Path: "/mnt/disks/ssd1"},
+					},
+					NodeAffinity: &corev1.VolumeNodeAffinity{
+						Required: &corev1.NodeSelector{
+							NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+								MatchExpressions: []corev1.NodeSelectorRequirement{{
+									Key:      corev1.LabelHostname,
+									Operator: corev1.NodeSelectorOpIn,
+									Values:   []string{"node-0"},
+								}},
+							}},
+						},
+					},
+				},
+				{
+					PersistentVolumeSource: corev1.PersistentVolumeSource{
+						Local: &corev1.LocalVolumeSource{Path: "/mnt/disks/ssd2"},
+					},
+				},
+				{
+					PersistentVolumeSource: corev1.PersistentVolumeSource{
+						CSI: &corev1.CSIPersistentVolumeSource{Driver: "rook-ceph.cephfs.csi.ceph.com", VolumeHandle: "cephfs-volume"},
+					},
+				},
+				{
+					PersistentVolumeSource: corev1.PersistentVolumeSource{
+						CSI: &corev1.CSIPersistentVolumeSource{Driver: "rook-ceph.rbd.csi.ceph.com", VolumeHandle: "rbd-volume"},
+					},
+				},
+				{
+					PersistentVolumeSource: corev1.PersistentVolumeSource{
+						CSI: &corev1.CSIPersistentVolumeSource{Driver: "custom.csi.example.com", VolumeHandle: "custom-volume"},
+					},
+				},
+				{
+					PersistentVolumeSource: corev1.PersistentVolumeSource{
+						CSI: &corev1.CSIPersistentVolumeSource{Driver: "unknown.csi.example.com", VolumeHandle: "unknown-volume"},
+					},
+				},
+				{
+					PersistentVolumeSource: corev1.PersistentVolumeSource{
+						NFS: &corev1.NFSVolumeSource{Server: "nfs", Path: "/export"},
+					},
+				},
+				nil,
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.VolumeIDs).To(Equal([]string{"node-0:/mnt/disks/ssd1", "rbd-volume", "custom-volume"}))
+	})
+})
@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metal
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/validation"
+	mcmclient "github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/client"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// IgnitionPartHandler serves the parts of split ignitions at <namespace>/<part-secret>, so that the part base URL of
+// the IgnitionSizeOptions can point to the driver. Only the part Secrets created by the driver in the metal namespace
+// are served, any other path is not found.
+type IgnitionPartHandler struct {
+	clientProvider *mcmclient.Provider
+	metalNamespace string
+}
+
+// NewIgnitionPartHandler returns a new handler serving the ignition parts stored in the metal namespace
+func NewIgnitionPartHandler(clientProvider *mcmclient.Provider, namespace string) *IgnitionPartHandler {
+	return &IgnitionPartHandler{
+		clientProvider: clientProvider,
+		metalNamespace: namespace,
+	}
+}
+
+// ServeHTTP responds to a GET of an ignition part with the unmodified ignition stored in the part Secret
+func (h *IgnitionPartHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	namespace, name, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if !ok || namespace != h.metalNamespace || name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	secret := &corev1.Secret{}
+	if err := h.clientProvider.SyncClient(func(metalClient client.Client) error {
+		return metalClient.Get(r.Context(), client.ObjectKey{Namespace: namespace, Name: name}, secret)
+	}); err != nil {
+		if apierrors.IsNotFound(err) {
+			http.NotFound(w, r)
+			return
+		}
+		klog.ErrorS(err, "Failed to get ignition part Secret", "name", name, "namespace", namespace)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	// only the parts are served, not the ignition Secrets of the Machines or any other Secret of the metal namespace
+	if secret.Labels[validation.LabelKeyManagedBy] != validation.LabelValueManagedBy || secret.Labels[validation.LabelKeyIgnitionSecretName] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	key := secret.Annotations[validation.AnnotationKeyIgnitionSecretKey]
+	if key == "" {
+		key = defaultIgnitionKey
+	}
+	part, ok := secret.Data[key]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	klog.V(3).InfoS("Serving ignition part", "name", name, "namespace", namespace, "ignitionSecretName", secret.Labels[validation.LabelKeyIgnitionSecretName])
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := w.Write(part); err != nil {
+		klog.ErrorS(err, "Failed to write ignition part", "name", name, "namespace", namespace)
+	}
+}
@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metal
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/validation"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/cmd"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("IgnitionPartHandler", func() {
+	ns, _, drv := SetupTest(cmd.NodeNamePolicyServerClaimName)
+
+	var server *httptest.Server
+	BeforeEach(func() {
+		server = httptest.NewServer(NewIgnitionPartHandler((*drv).(*metalDriver).clientProvider, ns.Name))
+		DeferCleanup(server.Close)
+	})
+
+	get := func(path string) (int, string, string) {
+		GinkgoHelper()
+		resp, err := http.Get(server.URL + path)
+		Expect(err).NotTo(HaveOccurred())
+		defer func() { _ = resp.Body.Close() }()
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return resp.StatusCode, resp.Header.Get("Content-Type"), string(body)
+	}
+
+	It("should serve the ignition stored in a part Secret", func(ctx SpecContext) {
+		partSecret := newIgnitionSecret(getIgnitionPartName("machine-0", 0), ns.Name, "custom", `{"ignition":{"version":"3.2.0"}}`)
+		partSecret.Labels[validation.LabelKeyIgnitionSecretName] = "machine-0"
+		Expect(k8sClient.Create(ctx, partSecret)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, partSecret)
+
+		code, contentType, body := get("/" + ns.Name + "/machine-0-part-0")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(contentType).To(Equal("application/json"))
+		Expect(body).To(Equal(`{"ignition":{"version":"3.2.0"}}`))
+	})
+
+	It("should not serve other Secrets of the metal namespace", func(ctx SpecContext) {
+		By("not serving the ignition Secret of a Machine")
+		ignitionSecret := newIgnitionSecret("machine-0", ns.Name, defaultIgnitionKey, `{"ignition":{"version":"3.2.0"}}`)
+		Expect(k8sClient.Create(ctx, ignitionSecret)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ignitionSecret)
+		code, _, _ := get("/" + ns.Name + "/machine-0")
+		Expect(code).To(Equal(http.StatusNotFound))
+
+		By("not serving Secrets which are not managed by the driver")
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "credentials-part-0",
+				Namespace: ns.Name,
+				Labels:    map[string]string{validation.LabelKeyIgnitionSecretName: "credentials"},
+			},
+			Data: map[string][]byte{defaultIgnitionKey: []byte("secret")},
+		}
+		Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, secret)
+		code, _, _ = get("/" + ns.Name + "/credentials-part-0")
+		Expect(code).To(Equal(http.StatusNotFound))
+
+		By("not serving missing Secrets and Secrets of other namespaces")
+		code, _, _ = get("/" + ns.Name + "/machine-1-part-0")
+		Expect(code).To(Equal(http.StatusNotFound))
+		code, _, _ = get("/default/machine-0-part-0")
+		Expect(code).To(Equal(http.StatusNotFound))
+		code, _, _ = get("/" + ns.Name)
+		Expect(code).To(Equal(http.StatusNotFound))
+	})
+})
@@ -7,12 +7,17 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"strings"
+	"time"
 
 	apiv1alpha1 "github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/v1alpha1"
 	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/validation"
+	mcmclient "github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/client"
 	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/ignition"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/metrics"
 	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
 
+	machinev1alpha1 "github.com/gardener/machine-controller-manager/pkg/apis/machine/v1alpha1"
 	"github.com/gardener/machine-controller-manager/pkg/util/provider/driver"
 	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/codes"
 	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/status"
@@ -20,6 +25,7 @@ import (
 	"github.com/imdario/mergo"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/ptr"
@@ -51,20 +57,24 @@ func (d *metalDriver) InitializeMachine(ctx context.Context, req *driver.Initial
 		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to get ServerClaim: %v", err))
 	}
 
+	if d.serverBindingTimedOut(serverClaim) {
+		return nil, d.releaseUnboundServerClaim(ctx, req.Machine, serverClaim)
+	}
 	if serverClaim.Spec.ServerRef == nil {
 		return nil, status.Error(codes.Internal, fmt.Sprintf("ServerClaim %s/%s still not bound", d.metalNamespace, req.Machine.Name))
 	}
+	d.observeServerClaimBound(req.MachineClass.Name, req.Machine.Name, serverClaim, getMetricLabelsForProviderSpec(req.MachineClass, providerSpec))
 
 	if err := d.createIPAddressClaims(ctx, req, serverClaim, providerSpec); err != nil {
 		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to create IPAddressClaims: %v", err))
 	}
 
-	addressesMetaData, err := d.collectIPAddressClaimsMetadata(ctx, req, providerSpec)
+	addressesMetaData, addresses, err := d.collectIPAddressClaimsMetadata(ctx, req, serverClaim, providerSpec)
 	if err != nil {
 		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to collect IPAddress metadata: %v", err))
 	}
 
-	if err := d.createIgnitionAndPowerOnServer(ctx, req, serverClaim, providerSpec, addressesMetaData); err != nil {
+	if err := d.createIgnitionAndPowerOnServer(ctx, req, serverClaim, providerSpec, addressesMetaData, addresses); err != nil {
 		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to update ignition and power on server: %v", err))
 	}
 
@@ -88,103 +98,184 @@ func isEmptyInitializeRequest(req *driver.InitializeMachineRequest) bool {
 func (d *metalDriver) createIPAddressClaims(ctx context.Context, req *driver.InitializeMachineRequest, serverClaim *metalv1alpha1.ServerClaim, providerSpec *apiv1alpha1.ProviderSpec) error {
 	klog.V(3).InfoS("Creating IPAddressClaims", "name", req.Machine.Name, "namespace", d.metalNamespace)
 
+	ipClaimNames, err := d.getIPAddressClaimNames(ctx, req.Machine.Name, providerSpec.IPAMConfig)
+	if err != nil {
+		return err
+	}
+
+	count := 0
 	for _, ipamConfig := range providerSpec.IPAMConfig {
 		if ipamConfig.IPAMRef == nil {
 			return status.Error(codes.Internal, fmt.Sprintf("IPAMRef of an IPAMConfig %q is not set", ipamConfig.MetadataKey))
 		}
 
-		ipClaim := &capiv1beta1.IPAddressClaim{
-			TypeMeta: metav1.TypeMeta{
-				APIVersion: capiv1beta1.GroupVersion.String(),
-				Kind:       "IPAddressClaim",
-			},
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      getIPAddressClaimName(req.Machine.Name, ipamConfig.MetadataKey),
-				Namespace: d.metalNamespace,
-				Labels: map[string]string{
-					validation.LabelKeyServerClaimName:      req.Machine.Name,
-					validation.LabelKeyServerClaimNamespace: d.metalNamespace,
+		for _, claimRef := range getIPAddressClaimRefs(req.Machine.Name, ipamConfig) {
+			ipClaim := &capiv1beta1.IPAddressClaim{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: capiv1beta1.GroupVersion.String(),
+					Kind:       "IPAddressClaim",
 				},
-			},
-			Spec: capiv1beta1.IPAddressClaimSpec{
-				PoolRef: corev1.TypedLocalObjectReference{
-					APIGroup: ptr.To(ipamConfig.IPAMRef.APIGroup),
-					Kind:     ipamConfig.IPAMRef.Kind,
-					Name:     ipamConfig.IPAMRef.Name,
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      ipClaimNames[claimRef.name],
+					Namespace: d.metalNamespace,
+					Labels: map[string]string{
+						validation.LabelKeyServerClaimName:      req.Machine.Name,
+						validation.LabelKeyServerClaimNamespace: d.metalNamespace,
+						validation.LabelKeyManagedBy:            validation.LabelValueManagedBy,
+					},
 				},
-			},
-		}
-
-		if err := controllerutil.SetOwnerReference(serverClaim, ipClaim, d.clientProvider.GetClientScheme()); err != nil {
-			return fmt.Errorf("failed to set owner reference for IPAddressClaim %q: %v", ipClaim.Name, err)
-		}
-
-		if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
-			return metalClient.Patch(ctx, ipClaim, client.Apply, fieldOwner, client.ForceOwnership) //nolint:staticcheck // SA1019: Client.Apply() requires ApplyConfiguration types not provided by cluster-api for IPAddressClaim
-		}); err != nil {
-			return fmt.Errorf("failed to create IPAddressClaim: %s", err.Error())
+				Spec: capiv1beta1.IPAddressClaimSpec{
+					PoolRef: corev1.TypedLocalObjectReference{
+						APIGroup: ptr.To(claimRef.ipamRef.APIGroup),
+						Kind:     claimRef.ipamRef.Kind,
+						Name:     claimRef.ipamRef.Name,
+					},
+				},
+			}
+
+			if err := controllerutil.SetOwnerReference(serverClaim, ipClaim, d.clientProvider.GetClientScheme()); err != nil {
+				return fmt.Errorf("failed to set owner reference for IPAddressClaim %q: %v", ipClaim.Name, err)
+			}
+
+			if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
+				return metalClient.Patch(ctx, ipClaim, client.Apply, fieldOwner, client.ForceOwnership) //nolint:staticcheck // SA1019: Client.Apply() requires ApplyConfiguration types not provided by cluster-api for IPAddressClaim
+			}); err != nil {
+				return fmt.Errorf("failed to create IPAddressClaim: %s", err.Error())
+			}
+			count++
 		}
 	}
 
-	klog.V(3).InfoS("Successfully created all IPAddressClaims", "count", len(providerSpec.IPAMConfig))
+	klog.V(3).InfoS("Successfully created all IPAddressClaims", "count", count)
 	return nil
 }
 
-// collectIPAddressClaimsMetadata collects the IPAddressClaims metadata for the machine
-func (d *metalDriver) collectIPAddressClaimsMetadata(ctx context.Context, req *driver.InitializeMachineRequest, providerSpec *apiv1alpha1.ProviderSpec) (map[string]any, error) {
+// collectIPAddressClaimsMetadata collects the IPAddressClaims metadata for the machine and the allocated addresses by metadata key.
+// The IPv6 address of a dual-stack network is added to the metadata of the network under the ipv6 key.
+func (d *metalDriver) collectIPAddressClaimsMetadata(ctx context.Context, req *driver.InitializeMachineRequest, serverClaim *metalv1alpha1.ServerClaim, providerSpec *apiv1alpha1.ProviderSpec) (map[string]any, map[string][]capiv1beta1.IPAddressSpec, error) {
 	klog.V(3).InfoS("Collecting IPAddressClaims metadata for machine", "name", req.Machine.Name, "namespace", d.metalNamespace)
 
+	ipClaimNames, err := d.getIPAddressClaimNames(ctx, req.Machine.Name, providerSpec.IPAMConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	addressesMetaData := make(map[string]any)
+	addresses := make(map[string][]capiv1beta1.IPAddressSpec)
 
 	for _, ipamConfig := range providerSpec.IPAMConfig {
-		ipAddrClaimName := getIPAddressClaimName(req.Machine.Name, ipamConfig.MetadataKey)
-		ipClaim := &capiv1beta1.IPAddressClaim{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      ipAddrClaimName,
-				Namespace: d.metalNamespace,
-			},
+		for _, claimRef := range getIPAddressClaimRefs(req.Machine.Name, ipamConfig) {
+			ipClaim := &capiv1beta1.IPAddressClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      ipClaimNames[claimRef.name],
+					Namespace: d.metalNamespace,
+				},
+			}
+
+			if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
+				return metalClient.Get(ctx, client.ObjectKeyFromObject(ipClaim), ipClaim)
+			}); err != nil {
+				return nil, nil, fmt.Errorf("failed to get IPAddressClaim %q: %w", client.ObjectKeyFromObject(ipClaim), err)
+			}
+
+			if ipClaim.Status.AddressRef.Name == "" {
+				return nil, nil, fmt.Errorf("IPAddressClaim %s/%s not bound", ipClaim.Namespace, ipClaim.Name)
+			}
+
+			ipAddr := &capiv1beta1.IPAddress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      ipClaim.Status.AddressRef.Name,
+					Namespace: ipClaim.Namespace,
+				},
+			}
+
+			if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
+				return metalClient.Get(ctx, client.ObjectKeyFromObject(ipAddr), ipAddr)
+			}); err != nil {
+				return nil, nil, fmt.Errorf("failed to get IPAddress %q: %w", client.ObjectKeyFromObject(ipAddr), err)
+			}
+
+			validationErr := validation.ValidateIPAddress(ipAddr, claimRef.family)
+			if validationErr.ToAggregate() != nil && len(validationErr.ToAggregate().Errors()) > 0 {
+				return nil, nil, fmt.Errorf("failed to validate IPAddress %s/%s: %v", ipAddr.Namespace, ipAddr.Name, validationErr.ToAggregate().Errors())
+			}
+
+			addressMetaData := map[string]any{
+				"ip":      ipAddr.Spec.Address,
+				"prefix":  ipAddr.Spec.Prefix,
+				"gateway": ipAddr.Spec.Gateway,
+			}
+			if claimRef.family == corev1.IPv6Protocol {
+				ipv4MetaData, ok := addressesMetaData[ipamConfig.MetadataKey].(map[string]any)
+				if !ok {
+					return nil, nil, fmt.Errorf("IPv6 address %s of metadata key %q has no IPv4 address to be added to", ipAddr.Spec.Address, ipamConfig.MetadataKey)
+				}
+				ipv4MetaData["ipv6"] = addressMetaData
+			} else {
+				addressesMetaData[ipamConfig.MetadataKey] = addressMetaData
+			}
+			addresses[ipamConfig.MetadataKey] = append(addresses[ipamConfig.MetadataKey], ipAddr.Spec)
+
+			klog.V(3).InfoS("IP address metadata found", "namespace", ipAddr.Namespace, "name", ipAddr.Name, "ip", ipAddr.Spec.Address, "prefix", ipAddr.Spec.Prefix, "gateway", ipAddr.Spec.Gateway)
+			if d.metricsTracker.firstObservation(req.MachineClass.Name, req.Machine.Name, "IPAddressClaim/"+ipClaim.Name) {
+				labels := getMetricLabelsForProviderSpec(req.MachineClass, providerSpec)
+				labels[metrics.LabelPool] = claimRef.ipamRef.Name
+				metrics.IPAddressClaimAllocationDuration.With(labels).Observe(time.Since(ipClaim.CreationTimestamp.Time).Seconds())
+			}
+			d.recordEventf(req.Machine, serverClaim, corev1.EventTypeNormal, EventReasonIPAddressAllocated, "Allocated IP address %s/%d from %s %q for metadata key %q", ipAddr.Spec.Address, ipAddr.Spec.Prefix, claimRef.ipamRef.Kind, claimRef.ipamRef.Name, ipamConfig.MetadataKey)
 		}
+	}
 
-		if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
-			return metalClient.Get(ctx, client.ObjectKeyFromObject(ipClaim), ipClaim)
-		}); err != nil {
-			return nil, fmt.Errorf("failed to get IPAddressClaim %q: %w", client.ObjectKeyFromObject(ipClaim), err)
-		}
+	klog.V(3).InfoS("Successfully processed all IPAMConfigs", "count", len(addressesMetaData))
+	return addressesMetaData, addresses, nil
+}
 
-		if ipClaim.Status.AddressRef.Name == "" {
-			return nil, fmt.Errorf("IPAddressClaim %s/%s not bound", ipClaim.Namespace, ipClaim.Name)
-		}
+// generateIgnition creates an ignition file for the machine and stores it in a secret
+func (d *metalDriver) generateIgnitionSecret(ctx context.Context, req *driver.InitializeMachineRequest, hostname string, providerSpec *apiv1alpha1.ProviderSpec, addressesMetaData map[string]any, addresses map[string][]capiv1beta1.IPAddressSpec, serverMetadata *ServerMetadata, networks []ignition.Network) (*corev1.Secret, []*corev1.Secret, error) {
+	klog.V(3).InfoS("Generating ignition secret for machine", "name", req.Machine.Name)
 
-		ipAddr := &capiv1beta1.IPAddress{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      ipClaim.Status.AddressRef.Name,
-				Namespace: ipClaim.Namespace,
-			},
-		}
+	config, err := d.getIgnitionConfig(ctx, req, hostname, providerSpec, addressesMetaData, addresses, serverMetadata, networks)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
-			return metalClient.Get(ctx, client.ObjectKeyFromObject(ipAddr), ipAddr)
-		}); err != nil {
-			return nil, fmt.Errorf("failed to get IPAddress %q: %w", client.ObjectKeyFromObject(ipAddr), err)
-		}
+	butane, err := ignition.RenderButane(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to render ignition for Machine %q: %w", client.ObjectKeyFromObject(req.Machine), err)
+	}
 
-		addressesMetaData[ipamConfig.MetadataKey] = map[string]any{
-			"ip":      ipAddr.Spec.Address,
-			"prefix":  ipAddr.Spec.Prefix,
-			"gateway": ipAddr.Spec.Gateway,
-		}
+	return d.newIgnitionSecrets(ctx, req, providerSpec, butane, config.CompressionThreshold)
+}
 
-		klog.V(3).InfoS("IP address metadata found", "namespace", ipAddr.Namespace, "name", ipAddr.Name, "ip", ipAddr.Spec.Address, "prefix", ipAddr.Spec.Prefix, "gateway", ipAddr.Spec.Gateway)
+// newIgnitionSecrets translates the rendered Butane config of the machine into its ignition Secret and the Secrets of
+// the parts of an ignition which exceeds the maximum size
+func (d *metalDriver) newIgnitionSecrets(ctx context.Context, req *driver.InitializeMachineRequest, providerSpec *apiv1alpha1.ProviderSpec, butane string, compressionThreshold int) (*corev1.Secret, []*corev1.Secret, error) {
+	ignitionContent, err := ignition.TranslateButane(butane, compressionThreshold)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to render ignition for Machine %q: %w", client.ObjectKeyFromObject(req.Machine), err)
 	}
 
-	klog.V(3).InfoS("Successfully processed all IPAMConfigs", "count", len(addressesMetaData))
-	return addressesMetaData, nil
-}
+	ignitionSecretName := d.getIgnitionNameForMachine(ctx, req.Machine.Name)
+	ignitionContent, parts, err := d.splitIgnition(ignitionSecretName, ignitionContent)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to split ignition for Machine %q: %w", client.ObjectKeyFromObject(req.Machine), err)
+	}
 
-// generateIgnition creates an ignition file for the machine and stores it in a secret
-func (d *metalDriver) generateIgnitionSecret(ctx context.Context, req *driver.InitializeMachineRequest, hostname string, providerSpec *apiv1alpha1.ProviderSpec, addressesMetaData map[string]any, serverMetadata *ServerMetadata) (*corev1.Secret, error) {
-	klog.V(3).InfoS("Generating ignition secret for machine", "name", req.Machine.Name)
+	ignitionSecretKey := getIgnitionSecretKey(providerSpec)
+	ignitionSecret := newIgnitionSecret(ignitionSecretName, d.metalNamespace, ignitionSecretKey, ignitionContent)
 
+	partSecrets := make([]*corev1.Secret, 0, len(parts))
+	for i, part := range parts {
+		partSecret := newIgnitionSecret(getIgnitionPartName(ignitionSecretName, i), d.metalNamespace, ignitionSecretKey, part)
+		partSecret.Labels[validation.LabelKeyIgnitionSecretName] = ignitionSecretName
+		partSecrets = append(partSecrets, partSecret)
+	}
+
+	return ignitionSecret, partSecrets, nil
+}
+
+// getIgnitionConfig returns the configuration the ignition of the machine is rendered from
+func (d *metalDriver) getIgnitionConfig(ctx context.Context, req *driver.InitializeMachineRequest, hostname string, providerSpec *apiv1alpha1.ProviderSpec, addressesMetaData map[string]any, addresses map[string][]capiv1beta1.IPAddressSpec, serverMetadata *ServerMetadata, networks []ignition.Network) (*ignition.Config, error) {
 	userData, ok := req.Secret.Data["userData"]
 	if !ok {
 		return nil, fmt.Errorf("failed to find user-data in Secret %q", client.ObjectKeyFromObject(req.Secret))
@@ -194,10 +285,18 @@ func (d *metalDriver) generateIgnitionSecret(ctx context.Context, req *driver.In
 		providerSpec.Metadata = make(map[string]any)
 	}
 
+	var server ignition.Server
 	if serverMetadata != nil {
+		server = serverMetadata.Server
 		metadata := map[string]any{}
-		if serverMetadata.LoopbackAddress != nil {
-			metadata["loopbackAddress"] = serverMetadata.LoopbackAddress.String()
+		if len(serverMetadata.LoopbackAddresses) > 0 {
+			// loopbackAddress holds the first address for backward compatibility
+			loopbackAddresses := make([]string, 0, len(serverMetadata.LoopbackAddresses))
+			for _, addr := range serverMetadata.LoopbackAddresses {
+				loopbackAddresses = append(loopbackAddresses, addr.String())
+			}
+			metadata["loopbackAddress"] = loopbackAddresses[0]
+			metadata["loopbackAddresses"] = loopbackAddresses
 		}
 		if err := mergo.Merge(&providerSpec.Metadata, metadata, mergo.WithOverride); err != nil {
 			return nil, fmt.Errorf("failed to merge server metadata into provider metadata: %w", err)
@@ -208,59 +307,328 @@ func (d *metalDriver) generateIgnitionSecret(ctx context.Context, req *driver.In
 		return nil, fmt.Errorf("failed to merge addresses metadata into provider metadata: %w", err)
 	}
 
-	config := &ignition.Config{
-		Hostname:         hostname,
-		UserData:         string(userData),
-		MetaData:         providerSpec.Metadata,
-		Ignition:         providerSpec.Ignition,
-		DnsServers:       providerSpec.DnsServers,
-		IgnitionOverride: providerSpec.IgnitionOverride,
+	osTemplate, err := getOSTemplate(ctx, providerSpec.TemplateRef, d.metalNamespace, d.clientProvider)
+	if err != nil {
+		return nil, err
 	}
 
-	ignitionContent, err := ignition.Render(config)
+	additionalIgnitions, err := resolveIgnitionRefs(ctx, req.Secret, providerSpec.IgnitionRefs, d.metalNamespace, d.clientProvider)
 	if err != nil {
-		return nil, fmt.Errorf("failed to render ignition for Machine %q: %w", client.ObjectKeyFromObject(req.Machine), err)
+		return nil, err
+	}
+
+	config := &ignition.Config{
+		Hostname:            hostname,
+		UserData:            string(userData),
+		MetaData:            providerSpec.Metadata,
+		Ignition:            providerSpec.Ignition,
+		AdditionalIgnitions: additionalIgnitions,
+		DnsServers:          providerSpec.DnsServers,
+		Server:              server,
+		Machine: ignition.Machine{
+			Name:        req.Machine.Name,
+			Labels:      req.Machine.Labels,
+			Annotations: req.Machine.Annotations,
+		},
+		MachineClassName:     req.MachineClass.Name,
+		Addresses:            getIgnitionAddresses(addresses),
+		Networks:             networks,
+		NetworkFormat:        ignition.NetworkFormat(providerSpec.NetworkFormat),
+		IgnitionOverride:     providerSpec.IgnitionOverride,
+		Template:             osTemplate,
+		CompressionThreshold: d.ignitionSizeOptions.CompressionThreshold,
+	}
+
+	return config, nil
+}
+
+// resolveIgnitionRefs returns the ignition configurations referenced by the provider spec in the given order. Missing
+// Secrets, ConfigMaps and keys of optional references are skipped. References to keys of the provider Secret are
+// skipped if it is nil, e.g. when a MachineClass is validated whose Secret does not exist. The error of a reference
+// is an ignitionRefError.
+func resolveIgnitionRefs(ctx context.Context, providerSecret *corev1.Secret, ignitionRefs []apiv1alpha1.IgnitionReference, metalNamespace string, clientProvider *mcmclient.Provider) ([]string, error) {
+	ignitions := make([]string, 0, len(ignitionRefs))
+	for i, ignitionRef := range ignitionRefs {
+		content, ok, err := resolveIgnitionRef(ctx, providerSecret, ignitionRef, metalNamespace, clientProvider)
+		if err != nil {
+			return nil, &ignitionRefError{index: i, err: err}
+		}
+		if ok {
+			ignitions = append(ignitions, content)
+		}
+	}
+	return ignitions, nil
+}
+
+// ignitionRefError is the error of resolving the ignition reference with the given index
+type ignitionRefError struct {
+	index int
+	err   error
+}
+
+func (e *ignitionRefError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ignitionRefError) Unwrap() error {
+	return e.err
+}
+
+// resolveIgnitionRef returns the ignition configuration of a reference, false is returned if it is skipped
+func resolveIgnitionRef(ctx context.Context, providerSecret *corev1.Secret, ignitionRef apiv1alpha1.IgnitionReference, metalNamespace string, clientProvider *mcmclient.Provider) (string, bool, error) {
+	switch {
+	case ignitionRef.ProviderSecretKey != "":
+		if providerSecret == nil {
+			return "", false, nil
+		}
+		content, ok := providerSecret.Data[ignitionRef.ProviderSecretKey]
+		if !ok {
+			return "", false, fmt.Errorf("failed to find key %q in Secret %q", ignitionRef.ProviderSecretKey, client.ObjectKeyFromObject(providerSecret))
+		}
+		return string(content), true, nil
+
+	case ignitionRef.SecretRef != nil:
+		secret := &corev1.Secret{}
+		secretKey := client.ObjectKey{Namespace: metalNamespace, Name: ignitionRef.SecretRef.Name}
+		if err := clientProvider.SyncClient(func(metalClient client.Client) error {
+			return metalClient.Get(ctx, secretKey, secret)
+		}); err != nil {
+			if apierrors.IsNotFound(err) && ptr.Deref(ignitionRef.SecretRef.Optional, false) {
+				return "", false, nil
+			}
+			return "", false, fmt.Errorf("failed to get ignition Secret %q: %w", secretKey, err)
+		}
+		content, ok := secret.Data[ignitionRef.SecretRef.Key]
+		if !ok {
+			if ptr.Deref(ignitionRef.SecretRef.Optional, false) {
+				return "", false, nil
+			}
+			return "", false, fmt.Errorf("ignition Secret %q has no key %q", secretKey, ignitionRef.SecretRef.Key)
+		}
+		return string(content), true, nil
+
+	case ignitionRef.ConfigMapRef != nil:
+		configMap := &corev1.ConfigMap{}
+		configMapKey := client.ObjectKey{Namespace: metalNamespace, Name: ignitionRef.ConfigMapRef.Name}
+		if err := clientProvider.SyncClient(func(metalClient client.Client) error {
+			return metalClient.Get(ctx, configMapKey, configMap)
+		}); err != nil {
+			if apierrors.IsNotFound(err) && ptr.Deref(ignitionRef.ConfigMapRef.Optional, false) {
+				return "", false, nil
+			}
+			return "", false, fmt.Errorf("failed to get ignition ConfigMap %q: %w", configMapKey, err)
+		}
+		content, ok := configMap.Data[ignitionRef.ConfigMapRef.Key]
+		if !ok {
+			if ptr.Deref(ignitionRef.ConfigMapRef.Optional, false) {
+				return "", false, nil
+			}
+			return "", false, fmt.Errorf("ignition ConfigMap %q has no key %q", configMapKey, ignitionRef.ConfigMapRef.Key)
+		}
+		return content, true, nil
+	}
+	return "", false, nil
+}
+
+// splitIgnition splits an ignition which exceeds the maximum size into a pointer config and parts, which are
+// served from the configured part base URL
+func (d *metalDriver) splitIgnition(ignitionSecretName, ignitionContent string) (string, []string, error) {
+	maxSize := d.ignitionSizeOptions.MaxSize
+	if maxSize <= 0 || len(ignitionContent) <= maxSize {
+		return ignitionContent, nil, nil
+	}
+
+	if d.ignitionSizeOptions.PartBaseURL == "" {
+		return "", nil, fmt.Errorf("ignition of %d bytes exceeds the maximum size of %d bytes and no URL to serve its parts from is configured", len(ignitionContent), maxSize)
+	}
+
+	klog.V(3).InfoS("Splitting ignition which exceeds the maximum size", "name", ignitionSecretName, "size", len(ignitionContent), "maxSize", maxSize)
+	baseURL := strings.TrimSuffix(d.ignitionSizeOptions.PartBaseURL, "/")
+	return ignition.Split(ignitionContent, maxSize, func(index int) string {
+		return fmt.Sprintf("%s/%s/%s", baseURL, d.metalNamespace, getIgnitionPartName(ignitionSecretName, index))
+	})
+}
+
+// getIgnitionAddresses converts the addresses allocated by IPAM for the ignition template
+func getIgnitionAddresses(addresses map[string][]capiv1beta1.IPAddressSpec) map[string][]ignition.Address {
+	ignitionAddresses := make(map[string][]ignition.Address, len(addresses))
+	for metadataKey, ipAddresses := range addresses {
+		for _, ipAddress := range ipAddresses {
+			ignitionAddresses[metadataKey] = append(ignitionAddresses[metadataKey], ignition.Address{
+				IP:      ipAddress.Address,
+				Prefix:  ipAddress.Prefix,
+				Gateway: ipAddress.Gateway,
+			})
+		}
+	}
+	return ignitionAddresses
+}
+
+// getIgnitionSecretKey returns the key the ignition is stored under in the ignition Secret
+func getIgnitionSecretKey(providerSpec *apiv1alpha1.ProviderSpec) string {
+	if providerSpec.IgnitionSecretKey == "" {
+		return defaultIgnitionKey
 	}
+	return providerSpec.IgnitionSecretKey
+}
 
-	ignitionData := map[string][]byte{}
-	ignitionData["ignition"] = []byte(ignitionContent)
-	ignitionSecret := &corev1.Secret{
+func newIgnitionSecret(name, namespace, key, content string) *corev1.Secret {
+	return &corev1.Secret{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: corev1.SchemeGroupVersion.String(),
 			Kind:       "Secret",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      d.getIgnitionNameForMachine(ctx, req.Machine.Name),
-			Namespace: d.metalNamespace,
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				validation.LabelKeyManagedBy: validation.LabelValueManagedBy,
+			},
+			Annotations: map[string]string{
+				validation.AnnotationKeyIgnitionSecretKey: key,
+			},
+		},
+		Data: map[string][]byte{
+			key: []byte(content),
 		},
-		Data: ignitionData,
 	}
+}
 
-	return ignitionSecret, nil
+// applyIgnitionSecret applies an ignition Secret and removes the ignition of an existing Secret which is stored under
+// a different key, e.g. after the IgnitionSecretKey of the provider spec has been changed. Secrets without the key
+// annotation were written before the key was configurable and store their ignition under the default key.
+func (d *metalDriver) applyIgnitionSecret(ctx context.Context, secret *corev1.Secret) error {
+	previousKey := ""
+	existing := &corev1.Secret{}
+	if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
+		return metalClient.Get(ctx, client.ObjectKeyFromObject(secret), existing)
+	}); err == nil {
+		previousKey = existing.Annotations[validation.AnnotationKeyIgnitionSecretKey]
+		if previousKey == "" {
+			previousKey = defaultIgnitionKey
+		}
+	} else if !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get ignition Secret %q: %w", client.ObjectKeyFromObject(secret), err)
+	}
+
+	key := secret.Annotations[validation.AnnotationKeyIgnitionSecretKey]
+	if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
+		return metalClient.Patch(ctx, secret, client.Apply, fieldOwner, client.ForceOwnership) //nolint:staticcheck // SA1019: Client.Apply() migration deferred until ServerClaim/IPAddressClaim apply configs are available
+	}); err != nil {
+		return err
+	}
+
+	// keys owned by the field owner are already removed by the apply
+	if _, ok := secret.Data[previousKey]; !ok || previousKey == key {
+		return nil
+	}
+
+	klog.V(3).InfoS("Removing ignition stored under a previous key", "name", secret.Name, "namespace", secret.Namespace, "previousKey", previousKey, "key", key)
+	secretBase := secret.DeepCopy()
+	delete(secret.Data, previousKey)
+	if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
+		return metalClient.Patch(ctx, secret, client.MergeFrom(secretBase))
+	}); err != nil {
+		return fmt.Errorf("failed to remove previous key %q of ignition Secret %q: %w", previousKey, client.ObjectKeyFromObject(secret), err)
+	}
+	return nil
 }
 
-// createIgnitionAndPowerOnServer creates the ignition secret for the server and powers it on
-func (d *metalDriver) createIgnitionAndPowerOnServer(ctx context.Context, req *driver.InitializeMachineRequest, serverClaim *metalv1alpha1.ServerClaim, providerSpec *apiv1alpha1.ProviderSpec, addressesMetaData map[string]any) error {
-	klog.V(3).InfoS("Creating ignition Secret and powering on server", "severClaimName", client.ObjectKeyFromObject(serverClaim))
+func getIgnitionPartName(ignitionSecretName string, index int) string {
+	return fmt.Sprintf("%s-part-%d", ignitionSecretName, index)
+}
+
+// getOSTemplate returns the OS template referenced by the ProviderSpec, which is either embedded in the driver or
+// read from a ConfigMap in the metal namespace. A nil template means that the default template is used.
+func getOSTemplate(ctx context.Context, templateRef *apiv1alpha1.TemplateReference, metalNamespace string, clientProvider *mcmclient.Provider) (*ignition.OSTemplate, error) {
+	if templateRef == nil {
+		return nil, nil
+	}
+
+	if templateRef.ConfigMapRef == nil {
+		osTemplate, ok := ignition.GetTemplate(templateRef.Name)
+		if !ok {
+			return nil, fmt.Errorf("template %q is not registered", templateRef.Name)
+		}
+		return osTemplate, nil
+	}
+
+	configMap := &corev1.ConfigMap{}
+	configMapKey := client.ObjectKey{Namespace: metalNamespace, Name: templateRef.ConfigMapRef.Name}
+	if err := clientProvider.SyncClient(func(metalClient client.Client) error {
+		return metalClient.Get(ctx, configMapKey, configMap)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to get template ConfigMap %q: %w", configMapKey, err)
+	}
 
+	content, ok := configMap.Data[templateRef.ConfigMapRef.Key]
+	if !ok {
+		return nil, fmt.Errorf("template ConfigMap %q has no key %q", configMapKey, templateRef.ConfigMapRef.Key)
+	}
+
+	return ignition.ParseTemplate(configMapKey.String(), content)
+}
+
+// getServerClaimIgnitionInputs returns the node name, the metadata and the network configuration of the Server bound to
+// a ServerClaim, which the ignition is rendered from. Warnings are emitted for the skipped loopback addresses and for the
+// addresses whose network interface cannot be determined, since they are not configured by the ignition.
+func (d *metalDriver) getServerClaimIgnitionInputs(ctx context.Context, machine *machinev1alpha1.Machine, serverClaim *metalv1alpha1.ServerClaim, providerSpec *apiv1alpha1.ProviderSpec, addresses map[string][]capiv1beta1.IPAddressSpec) (string, *ServerMetadata, []ignition.Network, error) {
 	nodeName, err := getNodeName(ctx, d.nodeNamePolicy, serverClaim, d.metalNamespace, d.clientProvider)
 	if err != nil {
-		return fmt.Errorf("failed to get node name: %w", err)
+		return "", nil, nil, fmt.Errorf("failed to get node name: %w", err)
 	}
 
 	serverMetadata, err := d.extractServerMetadataFromClaim(ctx, serverClaim)
 	if err != nil {
-		return fmt.Errorf("error extracting server metadata from ServerClaim %q: %w", client.ObjectKeyFromObject(serverClaim), err)
+		return "", nil, nil, fmt.Errorf("error extracting server metadata from ServerClaim %q: %w", client.ObjectKeyFromObject(serverClaim), err)
 	}
 
-	ignitionSecret, err := d.generateIgnitionSecret(ctx, req, nodeName, providerSpec, addressesMetaData, serverMetadata)
+	if len(serverMetadata.InvalidLoopbackAddresses) > 0 {
+		klog.InfoS("Skipping invalid loopback addresses of Server", "server", serverClaim.Spec.ServerRef.Name, "annotation", apiv1alpha1.LoopbackAddressAnnotation, "loopbackAddresses", serverMetadata.InvalidLoopbackAddresses)
+		d.recordEventf(machine, serverClaim, corev1.EventTypeWarning, EventReasonInvalidLoopbackAddress, "Skipped invalid loopback addresses %q in annotation %q of Server %q", serverMetadata.InvalidLoopbackAddresses, apiv1alpha1.LoopbackAddressAnnotation, serverClaim.Spec.ServerRef.Name)
+	}
+
+	networks, unmatchedKeys, err := getNetworks(providerSpec.IPAMConfig, addresses, serverMetadata.NetworkInterfaces)
 	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to get network configuration of ServerClaim %q: %w", client.ObjectKeyFromObject(serverClaim), err)
+	}
+	if len(unmatchedKeys) > 0 {
+		klog.InfoS("Not configuring addresses of IPAMConfigs without interface, network interface of the Server is ambiguous", "serverClaim", client.ObjectKeyFromObject(serverClaim), "metadataKeys", unmatchedKeys, "interfaces", len(serverMetadata.NetworkInterfaces))
+		d.recordEventf(machine, serverClaim, corev1.EventTypeWarning, EventReasonNetworkNotConfigured, "Addresses of IPAMConfigs %v are not configured, their network interface cannot be determined from the %d network interfaces of the Server, set the interface of the IPAMConfigs", unmatchedKeys, len(serverMetadata.NetworkInterfaces))
+	}
+
+	return nodeName, serverMetadata, networks, nil
+}
+
+// createIgnitionAndPowerOnServer creates the ignition secret for the server and powers it on
+func (d *metalDriver) createIgnitionAndPowerOnServer(ctx context.Context, req *driver.InitializeMachineRequest, serverClaim *metalv1alpha1.ServerClaim, providerSpec *apiv1alpha1.ProviderSpec, addressesMetaData map[string]any, addresses map[string][]capiv1beta1.IPAddressSpec) error {
+	klog.V(3).InfoS("Creating ignition Secret and powering on server", "severClaimName", client.ObjectKeyFromObject(serverClaim))
+
+	nodeName, serverMetadata, networks, err := d.getServerClaimIgnitionInputs(ctx, req.Machine, serverClaim, providerSpec, addresses)
+	if err != nil {
+		d.recordEventf(req.Machine, serverClaim, corev1.EventTypeWarning, EventReasonIgnitionRenderFailed, "Failed to get the inputs of the ignition: %v", err)
 		return err
 	}
 
-	if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
-		return metalClient.Patch(ctx, ignitionSecret, client.Apply, fieldOwner, client.ForceOwnership) //nolint:staticcheck // SA1019: Client.Apply() migration deferred until ServerClaim/IPAddressClaim apply configs are available
-	}); err != nil {
+	metricLabels := getMetricLabelsForProviderSpec(req.MachineClass, providerSpec)
+	renderStart := time.Now()
+	ignitionSecret, partSecrets, err := d.generateIgnitionSecret(ctx, req, nodeName, providerSpec, addressesMetaData, addresses, serverMetadata, networks)
+	if err != nil {
+		metrics.IgnitionRenderFailures.With(metricLabels).Inc()
+		d.recordEventf(req.Machine, serverClaim, corev1.EventTypeWarning, EventReasonIgnitionRenderFailed, "Failed to render ignition: %v", err)
+		return err
+	}
+	metrics.IgnitionRenderDuration.With(metricLabels).Observe(time.Since(renderStart).Seconds())
+	d.recordEventf(req.Machine, serverClaim, corev1.EventTypeNormal, EventReasonIgnitionRendered, "Rendered ignition into Secret %q", ignitionSecret.Name)
+
+	// the parts have to exist before the pointer config referencing them
+	for _, secret := range append(partSecrets, ignitionSecret) {
+		if err := d.applyIgnitionSecret(ctx, secret); err != nil {
+			return err
+		}
+	}
+
+	if err := d.deleteStaleIgnitionParts(ctx, ignitionSecret.Name, partSecrets); err != nil {
 		return err
 	}
 
@@ -279,12 +647,51 @@ func (d *metalDriver) createIgnitionAndPowerOnServer(ctx context.Context, req *d
 	}
 
 	klog.V(3).InfoS("ServerClaim powered on", "serverClaimName", client.ObjectKeyFromObject(serverClaim))
+	d.recordEventf(req.Machine, serverClaim, corev1.EventTypeNormal, EventReasonPoweredOn, "Powered on Server %q", serverClaim.Spec.ServerRef.Name)
+
+	return nil
+}
+
+// deleteStaleIgnitionParts deletes the parts of a previously split ignition which are not part of the current one
+func (d *metalDriver) deleteStaleIgnitionParts(ctx context.Context, ignitionSecretName string, partSecrets []*corev1.Secret) error {
+	secretList := &corev1.SecretList{}
+	if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
+		return metalClient.List(ctx, secretList, client.InNamespace(d.metalNamespace), client.MatchingLabels{
+			validation.LabelKeyIgnitionSecretName: ignitionSecretName,
+		})
+	}); err != nil {
+		return fmt.Errorf("failed to list ignition parts of Secret %q: %w", ignitionSecretName, err)
+	}
+
+	current := make(map[string]struct{}, len(partSecrets))
+	for _, partSecret := range partSecrets {
+		current[partSecret.Name] = struct{}{}
+	}
+
+	for _, secret := range secretList.Items {
+		if _, ok := current[secret.Name]; ok {
+			continue
+		}
+		klog.V(3).InfoS("Deleting stale ignition part", "name", secret.Name, "namespace", secret.Namespace)
+		if err := d.clientProvider.SyncClient(func(metalClient client.Client) error {
+			return metalClient.Delete(ctx, &secret)
+		}); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete stale ignition part %q: %w", client.ObjectKeyFromObject(&secret), err)
+		}
+	}
 
 	return nil
 }
 
 type ServerMetadata struct {
-	LoopbackAddress net.IP
+	// LoopbackAddresses are the IPv4 and IPv6 loopback addresses of the Server
+	LoopbackAddresses []net.IP
+	// InvalidLoopbackAddresses are the entries of the loopback address annotation of the Server which are no IP
+	// addresses and have been skipped
+	InvalidLoopbackAddresses []string
+	NetworkInterfaces        []metalv1alpha1.NetworkInterface
+	// Server identifies the Server in the ignition template
+	Server ignition.Server
 }
 
 func (d *metalDriver) extractServerMetadataFromClaim(ctx context.Context, claim *metalv1alpha1.ServerClaim) (*ServerMetadata, error) {
@@ -302,13 +709,27 @@ func (d *metalDriver) extractServerMetadataFromClaim(ctx context.Context, claim
 		return nil, fmt.Errorf("failed to get Server by reference %q: %w", claim.Spec.ServerRef.Name, err)
 	}
 
-	serverMetadata := &ServerMetadata{}
+	serverMetadata := &ServerMetadata{
+		NetworkInterfaces: server.Status.NetworkInterfaces,
+		Server: ignition.Server{
+			Name:         server.Name,
+			SerialNumber: server.Status.SerialNumber,
+			SystemUUID:   server.Spec.SystemUUID,
+		},
+	}
+	if server.Spec.BMCRef != nil {
+		serverMetadata.Server.BMCName = server.Spec.BMCRef.Name
+	}
 
-	loopbackAddress, ok := server.Annotations[apiv1alpha1.LoopbackAddressAnnotation]
-	if ok {
-		addr := net.ParseIP(loopbackAddress)
-		if addr != nil {
-			serverMetadata.LoopbackAddress = addr
+	// invalid entries are skipped, so that a typo in the annotation does not keep the Machine from being initialized
+	if loopbackAddresses, ok := server.Annotations[apiv1alpha1.LoopbackAddressAnnotation]; ok {
+		for loopbackAddress := range strings.SplitSeq(loopbackAddresses, ",") {
+			addr := net.ParseIP(strings.TrimSpace(loopbackAddress))
+			if addr == nil {
+				serverMetadata.InvalidLoopbackAddresses = append(serverMetadata.InvalidLoopbackAddresses, loopbackAddress)
+				continue
+			}
+			serverMetadata.LoopbackAddresses = append(serverMetadata.LoopbackAddresses, addr)
 		}
 	}
 
@@ -8,6 +8,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"maps"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
 
 	"github.com/gardener/machine-controller-manager/pkg/util/provider/driver"
 	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/codes"
@@ -22,6 +26,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/ptr"
 	capiv1beta1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
@@ -123,7 +128,7 @@ var _ = Describe("InitializeMachine", func() {
 		))
 
 		By("ensuring the cleanup of the machine")
-		DeferCleanup((*drv).DeleteMachine, &driver.DeleteMachineRequest{
+		DeferCleanup(deleteMachine, *drv, &driver.DeleteMachineRequest{
 			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
 			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
 			Secret:       providerSecret,
@@ -206,6 +211,7 @@ var _ = Describe("InitializeMachine", func() {
 				HaveField("ObjectMeta.Labels", map[string]string{
 					validation.LabelKeyServerClaimName:      machineName,
 					validation.LabelKeyServerClaimNamespace: ns.Name,
+					validation.LabelKeyManagedBy:            validation.LabelValueManagedBy,
 				}),
 				HaveField("ObjectMeta.OwnerReferences", ContainElement(
 					metav1.OwnerReference{
@@ -223,11 +229,10 @@ var _ = Describe("InitializeMachine", func() {
 					},
 				}),
 				)))
-			DeferCleanup(k8sClient.Delete, ipClaim)
 		}
 
 		By("ensuring the cleanup of the machine")
-		DeferCleanup((*drv).DeleteMachine, &driver.DeleteMachineRequest{
+		DeferCleanup(deleteMachine, *drv, &driver.DeleteMachineRequest{
 			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
 			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
 			Secret:       providerSecret,
@@ -344,13 +349,207 @@ var _ = Describe("InitializeMachine", func() {
 		))
 
 		By("ensuring the cleanup of the machine")
-		DeferCleanup((*drv).DeleteMachine, &driver.DeleteMachineRequest{
+		DeferCleanup(deleteMachine, *drv, &driver.DeleteMachineRequest{
 			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
 			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
 			Secret:       providerSecret,
 		})
 	})
 
+	It("should read the OS template from a ConfigMap in the metal namespace", func(ctx SpecContext) {
+		By("creating a template ConfigMap")
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: ns.Name,
+				Name:      "os-template",
+			},
+			Data: map[string]string{
+				"template": "variant: flatcar\nversion: 1.0.0\n",
+			},
+		}
+		Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, configMap)
+
+		clientProvider := (*drv).(*metalDriver).clientProvider
+		Expect(getOSTemplate(ctx, &v1alpha1.TemplateReference{
+			ConfigMapRef: &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: configMap.Name},
+				Key:                  "template",
+			},
+		}, ns.Name, clientProvider)).To(SatisfyAll(
+			HaveField("Variant", "flatcar"),
+			HaveField("Version", "1.0.0"),
+		))
+
+		By("failing if the key is missing in the ConfigMap")
+		_, err := getOSTemplate(ctx, &v1alpha1.TemplateReference{
+			ConfigMapRef: &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: configMap.Name},
+				Key:                  "foo",
+			},
+		}, ns.Name, clientProvider)
+		Expect(err).To(MatchError(fmt.Sprintf(`template ConfigMap "%s/os-template" has no key "foo"`, ns.Name)))
+
+		By("using the embedded templates by name")
+		Expect(getOSTemplate(ctx, &v1alpha1.TemplateReference{Name: "flatcar"}, ns.Name, clientProvider)).To(HaveField("Variant", "flatcar"))
+		for _, name := range v1alpha1.TemplateNames {
+			Expect(getOSTemplate(ctx, &v1alpha1.TemplateReference{Name: name}, ns.Name, clientProvider)).To(HaveField("Name", name))
+		}
+		Expect(getOSTemplate(ctx, nil, ns.Name, clientProvider)).To(BeNil())
+	})
+
+	It("should resolve ignition references to the provider Secret, Secrets and ConfigMaps", func(ctx SpecContext) {
+		By("creating an ignition Secret and ConfigMap")
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: ns.Name,
+				Name:      "ignition-secret",
+			},
+			Data: map[string][]byte{
+				"ignition": []byte("passwd: {}\n"),
+			},
+		}
+		Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, secret)
+
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: ns.Name,
+				Name:      "ignition-config",
+			},
+			Data: map[string]string{
+				"ignition": "systemd: {}\n",
+			},
+		}
+		Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, configMap)
+
+		clientProvider := (*drv).(*metalDriver).clientProvider
+		provider := &corev1.Secret{Data: map[string][]byte{"ignition": []byte("storage: {}\n")}}
+		Expect(resolveIgnitionRefs(ctx, provider, []v1alpha1.IgnitionReference{
+			{ConfigMapRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: configMap.Name}, Key: "ignition"}},
+			{ProviderSecretKey: "ignition"},
+			{SecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name}, Key: "ignition"}},
+			{SecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "missing"}, Key: "ignition", Optional: ptr.To(true)}},
+			{ConfigMapRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: configMap.Name}, Key: "foo", Optional: ptr.To(true)}},
+		}, ns.Name, clientProvider)).To(Equal([]string{"systemd: {}\n", "storage: {}\n", "passwd: {}\n"}))
+
+		By("failing if a required reference is missing")
+		_, err := resolveIgnitionRefs(ctx, provider, []v1alpha1.IgnitionReference{
+			{SecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name}, Key: "foo"}},
+		}, ns.Name, clientProvider)
+		Expect(err).To(MatchError(fmt.Sprintf(`ignition Secret "%s/ignition-secret" has no key "foo"`, ns.Name)))
+
+		_, err = resolveIgnitionRefs(ctx, provider, []v1alpha1.IgnitionReference{
+			{ConfigMapRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "missing"}, Key: "ignition"}},
+		}, ns.Name, clientProvider)
+		Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(`failed to get ignition ConfigMap "%s/missing"`, ns.Name))))
+	})
+
+	It("should move the ignition of an existing Secret to the configured key", func(ctx SpecContext) {
+		By("creating an ignition Secret written before the key was configurable")
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: ns.Name,
+				Name:      "machine-key-migration",
+			},
+			Data: map[string][]byte{
+				"ignition": []byte("old"),
+				"foo":      []byte("bar"),
+			},
+		}
+		Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, secret)
+
+		metalDrv := (*drv).(*metalDriver)
+		Expect(metalDrv.applyIgnitionSecret(ctx, newIgnitionSecret(secret.Name, ns.Name, "custom", "new"))).To(Succeed())
+		Eventually(Object(secret)).Should(SatisfyAll(
+			HaveField("Annotations", HaveKeyWithValue(validation.AnnotationKeyIgnitionSecretKey, "custom")),
+			HaveField("Data", Equal(map[string][]byte{"custom": []byte("new"), "foo": []byte("bar")})),
+		))
+
+		By("changing the key again")
+		Expect(metalDrv.applyIgnitionSecret(ctx, newIgnitionSecret(secret.Name, ns.Name, "ignition", "newer"))).To(Succeed())
+		Eventually(Object(secret)).Should(SatisfyAll(
+			HaveField("Annotations", HaveKeyWithValue(validation.AnnotationKeyIgnitionSecretKey, "ignition")),
+			HaveField("Data", Equal(map[string][]byte{"ignition": []byte("newer"), "foo": []byte("bar")})),
+		))
+	})
+
+	It("should split ignitions exceeding the maximum size into parts served from the part base URL", func() {
+		content := fmt.Sprintf(`{"ignition":{"version":"3.2.0"},"storage":{"files":[{"path":"/etc/foo","contents":{"source":"data:,%s"}}]},"systemd":{"units":[{"name":"foo.service","enabled":true}]}}`, strings.Repeat("a", 1000))
+		metalDrv := &metalDriver{
+			metalNamespace:      "metal",
+			ignitionSizeOptions: IgnitionSizeOptions{MaxSize: 1150},
+		}
+
+		By("failing if no part base URL is configured")
+		_, _, err := metalDrv.splitIgnition("machine-0", content)
+		Expect(err).To(MatchError(ContainSubstring("no URL to serve its parts from is configured")))
+
+		By("splitting the ignition")
+		metalDrv.ignitionSizeOptions.PartBaseURL = "https://ignition.example.com/"
+		pointer, parts, err := metalDrv.splitIgnition("machine-0", content)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parts).To(HaveLen(2))
+		Expect(pointer).To(SatisfyAll(
+			ContainSubstring(`"source":"https://ignition.example.com/metal/machine-0-part-0"`),
+			ContainSubstring(`"source":"https://ignition.example.com/metal/machine-0-part-1"`),
+		))
+
+		By("keeping ignitions within the maximum size")
+		metalDrv.ignitionSizeOptions.MaxSize = 2048
+		Expect(metalDrv.splitIgnition("machine-0", content)).To(Equal(content))
+	})
+
+	It("should warn about addresses whose network interface of a multi-NIC Server is ambiguous", func(ctx SpecContext) {
+		By("creating a server with two network interfaces")
+		server := &metalv1alpha1.Server{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "multi-nic-server",
+			},
+			Spec: metalv1alpha1.ServerSpec{
+				SystemUUID: "12345",
+			},
+		}
+		Expect(k8sClient.Create(ctx, server)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, server)
+		Eventually(UpdateStatus(server, func() {
+			server.Status.NetworkInterfaces = []metalv1alpha1.NetworkInterface{
+				{Name: "eth0", MACAddress: "aa:bb:cc:dd:ee:00"},
+				{Name: "eth1", MACAddress: "aa:bb:cc:dd:ee:01", IPs: []metalv1alpha1.IP{{Addr: netip.MustParseAddr("10.0.1.2")}}},
+			}
+		})).Should(Succeed())
+
+		machine := newMachine(ns, machineNamePrefix, 11, nil)
+		serverClaim := &metalv1alpha1.ServerClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      machine.Name,
+				Namespace: ns.Name,
+			},
+			Spec: metalv1alpha1.ServerClaimSpec{
+				ServerRef: &corev1.LocalObjectReference{Name: server.Name},
+			},
+		}
+		providerSpec := &v1alpha1.ProviderSpec{
+			IPAMConfig: []v1alpha1.IPAMConfig{{MetadataKey: "pool-a"}, {MetadataKey: "pool-b"}},
+		}
+		addresses := map[string][]capiv1beta1.IPAddressSpec{
+			"pool-a": {{Address: "10.0.0.10", Prefix: 24}},
+			"pool-b": {{Address: "10.0.1.10", Prefix: 24}},
+		}
+
+		By("configuring only the address in the subnet of a network interface")
+		_, _, networks, err := (*drv).(*metalDriver).getServerClaimIgnitionInputs(ctx, machine, serverClaim, providerSpec, addresses)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(networks).To(ConsistOf(HaveField("Name", "eth1")))
+
+		By("emitting a warning about the address which is not configured")
+		for _, events := range []chan string{recorder.Events, machineRecorder.Events} {
+			Eventually(events).Should(Receive(Equal(fmt.Sprintf("Warning %s Addresses of IPAMConfigs [pool-a] are not configured, their network interface cannot be determined from the 2 network interfaces of the Server, set the interface of the IPAMConfigs", EventReasonNetworkNotConfigured))))
+		}
+	})
+
 	It("should fail if the machine request is empty", func(ctx SpecContext) {
 		By("failing if the machine request is empty")
 		initializeMachineResponse, err := (*drv).InitializeMachine(ctx, nil)
@@ -383,6 +582,36 @@ var _ = Describe("InitializeMachine", func() {
 		Expect(err).Should(MatchError(status.Error(codes.InvalidArgument, `requested provider "foo" is not supported by the driver "ironcore-metal"`)))
 	})
 
+	It("should release the ServerClaim if no server is bound within the binding timeout", func(ctx SpecContext) {
+		machineIndex := 8
+		machineName := fmt.Sprintf("%s-%d", machineNamePrefix, machineIndex)
+
+		By("creating machine")
+		Expect((*drv).CreateMachine(ctx, &driver.CreateMachineRequest{
+			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
+			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
+			Secret:       providerSecret,
+		})).To(HaveField("NodeName", machineName))
+
+		By("failing to initialize the machine once the binding timeout has passed")
+		(*drv).(*metalDriver).serverBindingTimeout = time.Nanosecond
+		_, err := (*drv).InitializeMachine(ctx, &driver.InitializeMachineRequest{
+			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
+			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
+			Secret:       providerSecret,
+		})
+		Expect(err).To(MatchError(status.Error(codes.ResourceExhausted, fmt.Sprintf(`no server matching the selector %q was bound to ServerClaim %q in namespace %q within %s`, "instance-type=bar", machineName, ns.Name, time.Nanosecond))))
+
+		By("ensuring that the unbound ServerClaim has been deleted")
+		serverClaim := &metalv1alpha1.ServerClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      machineName,
+				Namespace: ns.Name,
+			},
+		}
+		Eventually(Get(serverClaim)).Should(Satisfy(apierrors.IsNotFound))
+	})
+
 	It("should fail if the provided secret do not contain userData", func(ctx SpecContext) {
 		By("failing if the provided secret do not contain userData")
 		notCompleteSecret := providerSecret.DeepCopy()
@@ -448,7 +677,7 @@ var _ = Describe("InitializeMachine", func() {
 		Expect(err).To(MatchError(status.Error(codes.Internal, fmt.Sprintf(`ServerClaim %s/%s still not bound`, ns.Name, machineName))))
 
 		By("ensuring the cleanup of the machine")
-		DeferCleanup((*drv).DeleteMachine, &driver.DeleteMachineRequest{
+		DeferCleanup(deleteMachine, *drv, &driver.DeleteMachineRequest{
 			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
 			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
 			Secret:       providerSecret,
@@ -516,10 +745,8 @@ var _ = Describe("InitializeMachine", func() {
 			g.Expect(err).To(MatchError(status.Error(codes.Internal, fmt.Sprintf("failed to collect IPAddress metadata: IPAddressClaim %s/%s-%s not bound", ns.Name, machineName, poolName))))
 		}).Should(Succeed())
 
-		DeferCleanup(k8sClient.Delete, ipClaim)
-
 		By("ensuring the cleanup of the machine")
-		DeferCleanup((*drv).DeleteMachine, &driver.DeleteMachineRequest{
+		DeferCleanup(deleteMachine, *drv, &driver.DeleteMachineRequest{
 			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
 			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
 			Secret:       providerSecret,
@@ -677,10 +904,48 @@ var _ = Describe("InitializeMachine with Server name as hostname", func() {
 		))
 
 		By("ensuring the cleanup of the machine")
-		DeferCleanup((*drv).DeleteMachine, &driver.DeleteMachineRequest{
+		DeferCleanup(deleteMachine, *drv, &driver.DeleteMachineRequest{
 			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
 			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
 			Secret:       providerSecret,
 		})
 	})
+
+	It("should skip invalid loopback addresses of a Server with a warning", func(ctx SpecContext) {
+		By("creating a server")
+		server := &metalv1alpha1.Server{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "loopback-server",
+				Annotations: map[string]string{
+					v1alpha1.LoopbackAddressAnnotation: "2001:db8::1,foo,",
+				},
+			},
+			Spec: metalv1alpha1.ServerSpec{
+				SystemUUID: "12345",
+			},
+		}
+		Expect(k8sClient.Create(ctx, server)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, server)
+
+		serverClaim := &metalv1alpha1.ServerClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "loopback-server-claim",
+				Namespace: ns.Name,
+			},
+			Spec: metalv1alpha1.ServerClaimSpec{
+				ServerRef: &corev1.LocalObjectReference{Name: server.Name},
+			},
+		}
+		serverMetadata, err := (*drv).(*metalDriver).extractServerMetadataFromClaim(ctx, serverClaim)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(serverMetadata.LoopbackAddresses).To(Equal([]net.IP{net.ParseIP("2001:db8::1")}))
+		Expect(serverMetadata.InvalidLoopbackAddresses).To(Equal([]string{"foo", ""}))
+
+		By("emitting a warning about the skipped loopback addresses")
+		_, _, _, err = (*drv).(*metalDriver).getServerClaimIgnitionInputs(ctx, newMachine(ns, machineNamePrefix, 12, nil), serverClaim, &v1alpha1.ProviderSpec{}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		for _, events := range []chan string{recorder.Events, machineRecorder.Events} {
+			Eventually(events).Should(Receive(Equal(fmt.Sprintf(`Warning %s Skipped invalid loopback addresses ["foo" ""] in annotation "metal.ironcore.dev/loopback-address" of Server "loopback-server"`, EventReasonInvalidLoopbackAddress))))
+		}
+	})
 })
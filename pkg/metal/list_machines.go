@@ -7,12 +7,14 @@ import (
 	"context"
 	"fmt"
 	"maps"
+	"time"
 
 	"github.com/gardener/machine-controller-manager/pkg/util/provider/driver"
 	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/codes"
 	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/status"
 	apiv1alpha1 "github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/v1alpha1"
 	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -38,6 +40,7 @@ func (d *metalDriver) ListMachines(ctx context.Context, req *driver.ListMachines
 	matchingLabels := client.MatchingLabels{}
 	maps.Copy(matchingLabels, providerSpec.Labels)
 
+	listedAt := time.Now()
 	if err = d.clientProvider.SyncClient(func(metalClient client.Client) error {
 		return metalClient.List(ctx, serverClaimList, client.InNamespace(d.metalNamespace), matchingLabels)
 	}); err != nil {
@@ -45,10 +48,14 @@ func (d *metalDriver) ListMachines(ctx context.Context, req *driver.ListMachines
 	}
 
 	machineList := make(map[string]string, len(serverClaimList.Items))
+	machineNames := sets.New[string]()
 	for _, machine := range serverClaimList.Items {
 		machineID := getProviderIDForServerClaim(&machine)
 		machineList[machineID] = machine.Name
+		machineNames.Insert(machine.Name)
 	}
+	// drop the metric observations of Machines whose ServerClaim is gone
+	d.metricsTracker.retain(req.MachineClass.Name, machineNames, listedAt)
 
 	return &driver.ListMachinesResponse{MachineList: machineList}, nil
 }
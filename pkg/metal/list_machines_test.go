@@ -82,7 +82,7 @@ var _ = Describe("ListMachines", func() {
 		))
 
 		By("ensuring the cleanup of the machine")
-		DeferCleanup((*drv).DeleteMachine, &driver.DeleteMachineRequest{
+		DeferCleanup(deleteMachine, *drv, &driver.DeleteMachineRequest{
 			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
 			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
 			Secret:       providerSecret,
@@ -154,14 +154,14 @@ var _ = Describe("ListMachines", func() {
 		}))
 
 		By("ensuring the cleanup of the first machine")
-		DeferCleanup((*drv).DeleteMachine, &driver.DeleteMachineRequest{
+		DeferCleanup(deleteMachine, *drv, &driver.DeleteMachineRequest{
 			Machine:      newMachine(ns, machineNamePrefix, machineIndex, nil),
 			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
 			Secret:       providerSecret,
 		})
 
 		By("ensuring the cleanup of the second machine")
-		DeferCleanup((*drv).DeleteMachine, &driver.DeleteMachineRequest{
+		DeferCleanup(deleteMachine, *drv, &driver.DeleteMachineRequest{
 			Machine:      newMachine(ns, machineNamePrefix, machineIndex2, nil),
 			MachineClass: newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec),
 			Secret:       providerSecret,
@@ -0,0 +1,259 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	apiv1alpha1 "github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/v1alpha1"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/validation"
+	mcmclient "github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/client"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/ignition"
+
+	machinev1alpha1 "github.com/gardener/machine-controller-manager/pkg/apis/machine/v1alpha1"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/yaml"
+)
+
+// MachineClassValidationPath is the path the MachineClass validation webhook is served at
+const MachineClassValidationPath = "/validate-machine-sapcloud-io-v1alpha1-machineclass"
+
+var machineClassGroupKind = schema.GroupKind{Group: "machine.sapcloud.io", Kind: "MachineClass"}
+
+// MachineClassValidator is a validating admission webhook for MachineClasses of the metal provider. Besides the
+// validation done by the driver, it checks that the ignition can be rendered and that the IPAM references point to
+// kinds served by the metal cluster, so that invalid MachineClasses are rejected before any Machine is created.
+type MachineClassValidator struct {
+	clientProvider *mcmclient.Provider
+	metalNamespace string
+	// controlClient reads the Secrets of the MachineClasses from the control cluster
+	controlClient client.Reader
+}
+
+// NewMachineClassValidator returns a new validator of MachineClasses
+func NewMachineClassValidator(clientProvider *mcmclient.Provider, namespace string, controlClient client.Reader) *MachineClassValidator {
+	return &MachineClassValidator{
+		clientProvider: clientProvider,
+		metalNamespace: namespace,
+		controlClient:  controlClient,
+	}
+}
+
+// Handle validates MachineClasses on create and update
+func (v *MachineClassValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	machineClass := &machinev1alpha1.MachineClass{}
+	if err := json.Unmarshal(req.Object.Raw, machineClass); err != nil {
+		return admission.Errored(http.StatusBadRequest, fmt.Errorf("failed to decode MachineClass: %w", err))
+	}
+	if machineClass.Provider != apiv1alpha1.ProviderName {
+		return admission.Allowed("")
+	}
+
+	klog.V(3).InfoS("Validating MachineClass", "name", machineClass.Name, "namespace", machineClass.Namespace)
+	allErrs, warnings, err := v.ValidateMachineClass(ctx, machineClass)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if len(allErrs) > 0 {
+		status := apierrors.NewInvalid(machineClassGroupKind, machineClass.Name, allErrs).ErrStatus
+		return admission.Response{
+			AdmissionResponse: admissionv1.AdmissionResponse{
+				Allowed: false,
+				Result:  &status,
+			},
+		}.WithWarnings(warnings...)
+	}
+	return admission.Allowed("").WithWarnings(warnings...)
+}
+
+// ValidateMachineClass validates the provider spec and Secret of a MachineClass. Referenced objects which do not
+// exist yet are reported as warnings, since they may be created after the MachineClass.
+func (v *MachineClassValidator) ValidateMachineClass(ctx context.Context, machineClass *machinev1alpha1.MachineClass) (field.ErrorList, []string, error) {
+	fldPath := field.NewPath("providerSpec")
+
+	providerSpec, warnings, allErrs, err := decodeProviderSpec(machineClass, fldPath)
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, string(machineClass.ProviderSpec.Raw), fmt.Sprintf("failed to decode provider spec: %v", err))}, nil, nil
+	}
+
+	secret, err := v.getSecret(ctx, machineClass.SecretRef)
+	if err != nil {
+		return nil, nil, err
+	}
+	if secret == nil && machineClass.SecretRef != nil {
+		warnings = append(warnings, fmt.Sprintf("Secret %s/%s does not exist, skipping its validation", machineClass.SecretRef.Namespace, machineClass.SecretRef.Name))
+		allErrs = append(allErrs, validation.ValidateProviderSpec(providerSpec, fldPath)...)
+	} else {
+		allErrs = append(allErrs, validation.ValidateProviderSpecAndSecret(providerSpec, secret, fldPath)...)
+	}
+
+	ipamErrs, err := v.validateIPAMRefs(providerSpec.IPAMConfig, fldPath.Child("ipamConfig"))
+	if err != nil {
+		return nil, nil, err
+	}
+	allErrs = append(allErrs, ipamErrs...)
+
+	ignitionErrs, ignitionWarnings := v.validateIgnition(ctx, machineClass.Name, providerSpec, secret, fldPath)
+	allErrs = append(allErrs, ignitionErrs...)
+	warnings = append(warnings, ignitionWarnings...)
+
+	return allErrs, warnings, nil
+}
+
+// getSecret returns the Secret of a MachineClass, nil is returned if it does not exist
+func (v *MachineClassValidator) getSecret(ctx context.Context, secretRef *corev1.SecretReference) (*corev1.Secret, error) {
+	if secretRef == nil {
+		return nil, nil
+	}
+
+	secret := &corev1.Secret{}
+	if err := v.controlClient.Get(ctx, client.ObjectKey{Namespace: secretRef.Namespace, Name: secretRef.Name}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get Secret %s/%s: %w", secretRef.Namespace, secretRef.Name, err)
+	}
+	return secret, nil
+}
+
+// validateIPAMRefs checks if the IPAM references are complete and point to kinds served by the metal cluster. The
+// name and kind of IPv6 IPAM references are already checked by the provider spec validation.
+func (v *MachineClassValidator) validateIPAMRefs(ipamConfigs []apiv1alpha1.IPAMConfig, fldPath *field.Path) (field.ErrorList, error) {
+	var allErrs field.ErrorList
+
+	for i, ipamConfig := range ipamConfigs {
+		ipamRefPath := fldPath.Index(i).Child("ipamRef")
+		if ipamConfig.IPAMRef == nil {
+			allErrs = append(allErrs, field.Required(ipamRefPath, "ipamRef is required"))
+			continue
+		}
+		if ipamConfig.IPAMRef.Name == "" {
+			allErrs = append(allErrs, field.Required(ipamRefPath.Child("name"), "name is required"))
+		}
+		if ipamConfig.IPAMRef.Kind == "" {
+			allErrs = append(allErrs, field.Required(ipamRefPath.Child("kind"), "kind is required"))
+		}
+
+		errs, err := v.validateIPAMRefKind(ipamConfig.IPAMRef, ipamRefPath)
+		if err != nil {
+			return nil, err
+		}
+		allErrs = append(allErrs, errs...)
+
+		if ipamConfig.IPv6IPAMRef != nil {
+			errs, err := v.validateIPAMRefKind(ipamConfig.IPv6IPAMRef, fldPath.Index(i).Child("ipv6IpamRef"))
+			if err != nil {
+				return nil, err
+			}
+			allErrs = append(allErrs, errs...)
+		}
+	}
+
+	return allErrs, nil
+}
+
+// validateIPAMRefKind checks if the API group of an IPAM reference is set and if its kind is served by the metal cluster
+func (v *MachineClassValidator) validateIPAMRefKind(ref *apiv1alpha1.IPAMObjectReference, fldPath *field.Path) (field.ErrorList, error) {
+	if ref.APIGroup == "" {
+		return field.ErrorList{field.Required(fldPath.Child("apiGroup"), "apiGroup is required")}, nil
+	}
+	if ref.Kind == "" {
+		return nil, nil
+	}
+
+	if err := v.clientProvider.SyncClient(func(metalClient client.Client) error {
+		_, err := metalClient.RESTMapper().RESTMapping(schema.GroupKind{Group: ref.APIGroup, Kind: ref.Kind})
+		return err
+	}); err != nil {
+		if !meta.IsNoMatchError(err) {
+			return nil, fmt.Errorf("failed to get REST mapping of %s.%s: %w", ref.Kind, ref.APIGroup, err)
+		}
+		return field.ErrorList{field.Invalid(fldPath.Child("kind"), ref.Kind, fmt.Sprintf("kind is not served by API group %q in the metal cluster", ref.APIGroup))}, nil
+	}
+	return nil, nil
+}
+
+// validateIgnition checks if the ignition of the provider spec is valid YAML, if the ignition references can be
+// resolved and if the ignition of a Machine can be rendered from the provider spec, the referenced ignitions and the
+// user data of the Secret
+func (v *MachineClassValidator) validateIgnition(ctx context.Context, hostname string, providerSpec *apiv1alpha1.ProviderSpec, secret *corev1.Secret, fldPath *field.Path) (field.ErrorList, []string) {
+	if providerSpec.Ignition != "" {
+		if err := yaml.Unmarshal([]byte(providerSpec.Ignition), &map[string]any{}); err != nil {
+			return field.ErrorList{field.Invalid(fldPath.Child("ignition"), providerSpec.Ignition, fmt.Sprintf("ignition is not valid YAML: %v", err))}, nil
+		}
+	}
+
+	additionalIgnitions, err := resolveIgnitionRefs(ctx, secret, providerSpec.IgnitionRefs, v.metalNamespace, v.clientProvider)
+	if err != nil {
+		var refErr *ignitionRefError
+		if errors.As(err, &refErr) {
+			return field.ErrorList{field.Invalid(fldPath.Child("ignitionRefs").Index(refErr.index), providerSpec.IgnitionRefs[refErr.index], err.Error())}, nil
+		}
+		return field.ErrorList{field.Invalid(fldPath.Child("ignitionRefs"), providerSpec.IgnitionRefs, err.Error())}, nil
+	}
+
+	osTemplate, err := getOSTemplate(ctx, providerSpec.TemplateRef, v.metalNamespace, v.clientProvider)
+	if err != nil {
+		return nil, []string{fmt.Sprintf("Failed to get OS template, skipping the rendering of the ignition: %v", err)}
+	}
+
+	if osTemplate != nil && providerSpec.NetworkFormat == "" && len(providerSpec.IPAMConfig) > 0 {
+		if _, err := osTemplate.NetworkFormat(); err != nil {
+			return field.ErrorList{field.Required(fldPath.Child("networkFormat"), err.Error())}, nil
+		}
+	}
+
+	// the Machine and Server are not known yet, so the template is rendered with placeholders
+	config := &ignition.Config{
+		Hostname:            hostname,
+		MetaData:            providerSpec.Metadata,
+		Ignition:            providerSpec.Ignition,
+		AdditionalIgnitions: additionalIgnitions,
+		IgnitionOverride:    providerSpec.IgnitionOverride,
+		DnsServers:          providerSpec.DnsServers,
+		Machine:             ignition.Machine{Name: hostname},
+		MachineClassName:    hostname,
+		Addresses:           getPlaceholderAddresses(providerSpec.IPAMConfig),
+		Template:            osTemplate,
+		NetworkFormat:       ignition.NetworkFormat(providerSpec.NetworkFormat),
+	}
+	if secret != nil {
+		config.UserData = string(secret.Data["userData"])
+	}
+	if _, err := ignition.Render(config); err != nil {
+		return field.ErrorList{field.Invalid(fldPath.Child("ignition"), providerSpec.Ignition, fmt.Sprintf("failed to render ignition: %v", err))}, nil
+	}
+
+	return nil, nil
+}
+
+// getPlaceholderAddresses returns documentation addresses for the IPAM configs, so that templates accessing the
+// addresses of a Machine can be rendered
+func getPlaceholderAddresses(ipamConfigs []apiv1alpha1.IPAMConfig) map[string][]ignition.Address {
+	addresses := make(map[string][]ignition.Address, len(ipamConfigs))
+	for _, ipamConfig := range ipamConfigs {
+		addresses[ipamConfig.MetadataKey] = []ignition.Address{{IP: "192.0.2.1", Prefix: 24, Gateway: "192.0.2.254"}}
+		if ipamConfig.IPv6IPAMRef != nil {
+			addresses[ipamConfig.MetadataKey] = append(addresses[ipamConfig.MetadataKey], ignition.Address{IP: "2001:db8::1", Prefix: 64, Gateway: "2001:db8::fffe"})
+		}
+	}
+	return addresses
+}
@@ -0,0 +1,211 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metal
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+
+	gardenermachinev1alpha1 "github.com/gardener/machine-controller-manager/pkg/apis/machine/v1alpha1"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/v1alpha1"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/cmd"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/metal/testing"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("MachineClassValidator", func() {
+	ns, providerSecret, drv := SetupTest(cmd.NodeNamePolicyServerClaimName)
+
+	var validator *MachineClassValidator
+	BeforeEach(func() {
+		validator = NewMachineClassValidator((*drv).(*metalDriver).clientProvider, ns.Name, k8sClient)
+	})
+
+	// the legacy fields of the sample provider spec are accepted with warnings
+	newProviderSpec := func() map[string]any {
+		providerSpec := maps.Clone(testing.SampleProviderSpec)
+		for _, key := range []string{"metaData", "ignitionSecret", "machineClassName", "machinePoolName"} {
+			delete(providerSpec, key)
+		}
+		return providerSpec
+	}
+
+	newValidatedMachineClass := func(providerSpec map[string]any) *gardenermachinev1alpha1.MachineClass {
+		machineClass := newMachineClass(v1alpha1.ProviderName, providerSpec)
+		machineClass.Name = "machine-class"
+		machineClass.SecretRef = &corev1.SecretReference{Namespace: ns.Name, Name: providerSecret.Name}
+		return machineClass
+	}
+
+	It("should accept a valid MachineClass", func(ctx SpecContext) {
+		allErrs, warnings, err := validator.ValidateMachineClass(ctx, newValidatedMachineClass(newProviderSpec()))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allErrs).To(BeEmpty())
+		Expect(warnings).To(BeEmpty())
+	})
+
+	It("should reject an ignition which is not valid YAML", func(ctx SpecContext) {
+		providerSpec := newProviderSpec()
+		providerSpec["ignition"] = "passwd: [users"
+
+		allErrs, _, err := validator.ValidateMachineClass(ctx, newValidatedMachineClass(providerSpec))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allErrs).To(ConsistOf(HaveField("Field", "providerSpec.ignition")))
+		Expect(allErrs.ToAggregate().Error()).To(ContainSubstring("ignition is not valid YAML"))
+	})
+
+	It("should reject an ignition which cannot be rendered", func(ctx SpecContext) {
+		providerSpec := newProviderSpec()
+		providerSpec["ignition"] = "storage:\n  files: foo\n"
+
+		allErrs, _, err := validator.ValidateMachineClass(ctx, newValidatedMachineClass(providerSpec))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allErrs).To(ConsistOf(HaveField("Field", "providerSpec.ignition")))
+		Expect(allErrs.ToAggregate().Error()).To(ContainSubstring("failed to render ignition"))
+	})
+
+	It("should reject ignition references which cannot be resolved", func(ctx SpecContext) {
+		providerSpec := newProviderSpec()
+		providerSpec["ignitionRefs"] = []v1alpha1.IgnitionReference{
+			{ConfigMapRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "missing"}, Key: "ignition", Optional: ptr.To(true)}},
+			{SecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "missing"}, Key: "ignition"}},
+		}
+
+		allErrs, _, err := validator.ValidateMachineClass(ctx, newValidatedMachineClass(providerSpec))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allErrs).To(ConsistOf(HaveField("Field", "providerSpec.ignitionRefs[1]")))
+		Expect(allErrs.ToAggregate().Error()).To(ContainSubstring(fmt.Sprintf(`failed to get ignition Secret "%s/missing"`, ns.Name)))
+	})
+
+	It("should require the network format if the one of the OS template is unknown", func(ctx SpecContext) {
+		By("creating a template ConfigMap with a custom Butane variant")
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: ns.Name,
+				Name:      "custom-os-template",
+			},
+			Data: map[string]string{
+				"template": "variant: custom\nversion: 1.0.0\n",
+			},
+		}
+		Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, configMap)
+
+		providerSpec := newProviderSpec()
+		providerSpec["templateRef"] = v1alpha1.TemplateReference{
+			ConfigMapRef: &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: configMap.Name},
+				Key:                  "template",
+			},
+		}
+		providerSpec["ipamConfig"] = []v1alpha1.IPAMConfig{{
+			MetadataKey: "pool-a",
+			IPAMRef:     &v1alpha1.IPAMObjectReference{APIGroup: "ipam.cluster.x-k8s.io", Kind: "GlobalInClusterIPPool", Name: "pool-a"},
+		}}
+
+		allErrs, _, err := validator.ValidateMachineClass(ctx, newValidatedMachineClass(providerSpec))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allErrs).To(ContainElement(HaveField("Field", "providerSpec.networkFormat")))
+	})
+
+	It("should check the IPAM references against the kinds served by the metal cluster", func(ctx SpecContext) {
+		By("installing the CRD of an IPAM pool")
+		_, err := envtest.InstallCRDs(cfg, envtest.CRDInstallOptions{
+			CRDs: []*apiextensionsv1.CustomResourceDefinition{{
+				ObjectMeta: metav1.ObjectMeta{Name: "globalinclusterippools.ipam.cluster.x-k8s.io"},
+				Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+					Group: "ipam.cluster.x-k8s.io",
+					Names: apiextensionsv1.CustomResourceDefinitionNames{
+						Kind:     "GlobalInClusterIPPool",
+						ListKind: "GlobalInClusterIPPoolList",
+						Plural:   "globalinclusterippools",
+						Singular: "globalinclusterippool",
+					},
+					Scope: apiextensionsv1.ClusterScoped,
+					Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{
+						Name:    "v1alpha2",
+						Served:  true,
+						Storage: true,
+						Schema: &apiextensionsv1.CustomResourceValidation{
+							OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+								Type:                   "object",
+								XPreserveUnknownFields: ptr.To(true),
+							},
+						},
+					}},
+				},
+			}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		providerSpec := newProviderSpec()
+		providerSpec["ipamConfig"] = []v1alpha1.IPAMConfig{
+			{
+				MetadataKey: "pool-a",
+				IPAMRef:     &v1alpha1.IPAMObjectReference{APIGroup: "ipam.cluster.x-k8s.io", Kind: "GlobalInClusterIPPool", Name: "pool-a"},
+			},
+			{
+				MetadataKey: "pool-b",
+				IPAMRef:     &v1alpha1.IPAMObjectReference{APIGroup: "ipam.cluster.x-k8s.io", Kind: "FooPool", Name: "pool-b"},
+				IPv6IPAMRef: &v1alpha1.IPAMObjectReference{Kind: "GlobalInClusterIPPool", Name: "pool-b-ipv6"},
+			},
+			{
+				MetadataKey: "pool-c",
+			},
+		}
+
+		allErrs, _, err := validator.ValidateMachineClass(ctx, newValidatedMachineClass(providerSpec))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allErrs).To(ConsistOf(
+			field.Invalid(field.NewPath("providerSpec", "ipamConfig").Index(1).Child("ipamRef", "kind"), "FooPool", `kind is not served by API group "ipam.cluster.x-k8s.io" in the metal cluster`),
+			field.Required(field.NewPath("providerSpec", "ipamConfig").Index(1).Child("ipv6IpamRef", "apiGroup"), "apiGroup is required"),
+			field.Required(field.NewPath("providerSpec", "ipamConfig").Index(2).Child("ipamRef"), "ipamRef is required"),
+		))
+	})
+
+	It("should warn if the Secret of the MachineClass does not exist", func(ctx SpecContext) {
+		machineClass := newValidatedMachineClass(newProviderSpec())
+		machineClass.SecretRef.Name = "missing"
+
+		allErrs, warnings, err := validator.ValidateMachineClass(ctx, machineClass)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allErrs).To(BeEmpty())
+		Expect(warnings).To(ConsistOf(ContainSubstring("does not exist, skipping its validation")))
+	})
+
+	It("should deny invalid MachineClasses of the metal provider only", func(ctx SpecContext) {
+		providerSpec := newProviderSpec()
+		delete(providerSpec, "image")
+
+		newRequest := func(provider string) admission.Request {
+			machineClass := newValidatedMachineClass(providerSpec)
+			machineClass.Provider = provider
+			raw, err := json.Marshal(machineClass)
+			Expect(err).NotTo(HaveOccurred())
+			return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: raw},
+			}}
+		}
+
+		response := validator.Handle(ctx, newRequest(v1alpha1.ProviderName))
+		Expect(response.Allowed).To(BeFalse())
+		Expect(response.Result.Code).To(BeEquivalentTo(http.StatusUnprocessableEntity))
+		Expect(response.Result.Message).To(ContainSubstring("image is required"))
+
+		Expect(validator.Handle(ctx, newRequest("foo")).Allowed).To(BeTrue())
+	})
+})
@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metal
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	machinev1alpha1 "github.com/gardener/machine-controller-manager/pkg/apis/machine/v1alpha1"
+	apiv1alpha1 "github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/v1alpha1"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// machineMetricsTracker remembers per Machine which one-time observations were already made, since the driver
+// methods are retried by MCM and would otherwise observe the same transition multiple times
+type machineMetricsTracker struct {
+	mu sync.Mutex
+	// observed holds the keys of the observations made per Machine
+	observed map[string]map[string]struct{}
+	// deletionStarted holds the time of the first deletion request per Machine
+	deletionStarted map[string]time.Time
+	// tracked holds the MachineClass of every tracked Machine and since when it is tracked
+	tracked map[string]trackedMachine
+}
+
+type trackedMachine struct {
+	machineClassName string
+	since            time.Time
+}
+
+func newMachineMetricsTracker() *machineMetricsTracker {
+	return &machineMetricsTracker{
+		observed:        map[string]map[string]struct{}{},
+		deletionStarted: map[string]time.Time{},
+		tracked:         map[string]trackedMachine{},
+	}
+}
+
+// track remembers the MachineClass of a Machine, t.mu must be locked
+func (t *machineMetricsTracker) track(machineClassName, machineName string) {
+	if _, ok := t.tracked[machineName]; !ok {
+		t.tracked[machineName] = trackedMachine{machineClassName: machineClassName, since: time.Now()}
+	}
+}
+
+// firstObservation returns true if the observation with key has not been made for the Machine yet
+func (t *machineMetricsTracker) firstObservation(machineClassName, machineName, key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.track(machineClassName, machineName)
+	if _, ok := t.observed[machineName][key]; ok {
+		return false
+	}
+	if t.observed[machineName] == nil {
+		t.observed[machineName] = map[string]struct{}{}
+	}
+	t.observed[machineName][key] = struct{}{}
+	return true
+}
+
+// deletionStartTime returns the time of the first deletion request of the Machine
+func (t *machineMetricsTracker) deletionStartTime(machineClassName, machineName string) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.track(machineClassName, machineName)
+	if started, ok := t.deletionStarted[machineName]; ok {
+		return started
+	}
+	t.deletionStarted[machineName] = time.Now()
+	return t.deletionStarted[machineName]
+}
+
+// forget drops everything remembered about the Machine
+func (t *machineMetricsTracker) forget(machineName string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.observed, machineName)
+	delete(t.deletionStarted, machineName)
+	delete(t.tracked, machineName)
+}
+
+// retain drops everything remembered about the Machines of a MachineClass which are not in machineNames, e.g. since
+// their creation failed or they were deleted by another instance of the driver. Machines tracked after listedAt are
+// kept, since they may have been created after machineNames was listed.
+func (t *machineMetricsTracker) retain(machineClassName string, machineNames sets.Set[string], listedAt time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for machineName, tracked := range t.tracked {
+		if tracked.machineClassName != machineClassName || machineNames.Has(machineName) || tracked.since.After(listedAt) {
+			continue
+		}
+		delete(t.observed, machineName)
+		delete(t.deletionStarted, machineName)
+		delete(t.tracked, machineName)
+	}
+}
+
+// getMetricLabels returns the MachineClass name and the server selector of a MachineClass as metric labels.
+// The provider spec is decoded without validation, so that metrics can also be recorded for invalid classes.
+func getMetricLabels(machineClass *machinev1alpha1.MachineClass) prometheus.Labels {
+	providerSpec := &apiv1alpha1.ProviderSpec{}
+	_ = json.Unmarshal(machineClass.ProviderSpec.Raw, providerSpec)
+	return getMetricLabelsForProviderSpec(machineClass, providerSpec)
+}
+
+// getMetricLabelsForProviderSpec returns the MachineClass name and the server selector of a ProviderSpec as metric labels
+func getMetricLabelsForProviderSpec(machineClass *machinev1alpha1.MachineClass, providerSpec *apiv1alpha1.ProviderSpec) prometheus.Labels {
+	return prometheus.Labels{
+		metrics.LabelMachineClass:   machineClass.Name,
+		metrics.LabelServerSelector: metav1.FormatLabelSelector(providerSpec.ServerSelector()),
+	}
+}
@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metal
+
+import (
+	"time"
+
+	gardenermachinev1alpha1 "github.com/gardener/machine-controller-manager/pkg/apis/machine/v1alpha1"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/v1alpha1"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/metal/testing"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/metrics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+var _ = Describe("Metrics", func() {
+	It("should make one-time observations only once per machine", func() {
+		tracker := newMachineMetricsTracker()
+		Expect(tracker.firstObservation("foo", "machine-0", "ServerClaimBound")).To(BeTrue())
+		Expect(tracker.firstObservation("foo", "machine-0", "ServerClaimBound")).To(BeFalse())
+		Expect(tracker.firstObservation("foo", "machine-1", "ServerClaimBound")).To(BeTrue())
+
+		deletionStarted := tracker.deletionStartTime("foo", "machine-0")
+		Expect(tracker.deletionStartTime("foo", "machine-0")).To(Equal(deletionStarted))
+
+		tracker.forget("machine-0")
+		Expect(tracker.firstObservation("foo", "machine-0", "ServerClaimBound")).To(BeTrue())
+		Expect(tracker.deletionStartTime("foo", "machine-0")).To(BeTemporally(">=", deletionStarted))
+	})
+
+	It("should drop the observations of machines which are not listed anymore", func() {
+		tracker := newMachineMetricsTracker()
+		Expect(tracker.firstObservation("foo", "machine-0", "ServerClaimBound")).To(BeTrue())
+		Expect(tracker.firstObservation("foo", "machine-1", "ServerClaimBound")).To(BeTrue())
+		Expect(tracker.firstObservation("bar", "machine-2", "ServerClaimBound")).To(BeTrue())
+		listedAt := time.Now()
+		Expect(tracker.firstObservation("foo", "machine-3", "ServerClaimBound")).To(BeTrue())
+
+		tracker.retain("foo", sets.New("machine-1"), listedAt)
+
+		By("dropping the machines of the MachineClass tracked before the listing")
+		Expect(tracker.firstObservation("foo", "machine-0", "ServerClaimBound")).To(BeTrue())
+		By("keeping listed machines, machines of other MachineClasses and machines tracked after the listing")
+		Expect(tracker.firstObservation("foo", "machine-1", "ServerClaimBound")).To(BeFalse())
+		Expect(tracker.firstObservation("bar", "machine-2", "ServerClaimBound")).To(BeFalse())
+		Expect(tracker.firstObservation("foo", "machine-3", "ServerClaimBound")).To(BeFalse())
+	})
+
+	It("should label metrics by MachineClass and server selector", func() {
+		machineClass := newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec)
+		machineClass.ObjectMeta = metav1.ObjectMeta{Name: "foo"}
+		Expect(getMetricLabels(machineClass)).To(Equal(prometheus.Labels{
+			metrics.LabelMachineClass:   "foo",
+			metrics.LabelServerSelector: "instance-type=bar",
+		}))
+
+		Expect(getMetricLabels(&gardenermachinev1alpha1.MachineClass{})).To(Equal(prometheus.Labels{
+			metrics.LabelMachineClass:   "",
+			metrics.LabelServerSelector: "<none>",
+		}))
+	})
+})
@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metal
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+
+	apiv1alpha1 "github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/v1alpha1"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/ignition"
+	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
+	capiv1beta1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
+)
+
+// getNetworks returns the static network configuration of the Server network interfaces from the addresses
+// allocated for the IPAM configs. Addresses of dual-stack networks and of IPAM configs targeting the same interface
+// are configured together. The metadata keys of the IPAM configs without interface whose network interface cannot be
+// determined are returned as well, their addresses are not configured.
+func getNetworks(ipamConfigs []apiv1alpha1.IPAMConfig, addresses map[string][]capiv1beta1.IPAddressSpec, networkInterfaces []metalv1alpha1.NetworkInterface) ([]ignition.Network, []string, error) {
+	var (
+		networks      []ignition.Network
+		unmatchedKeys []string
+		networkIndex  = map[string]int{}
+	)
+
+	for _, ipamConfig := range ipamConfigs {
+		ipamAddresses, ok := addresses[ipamConfig.MetadataKey]
+		if !ok {
+			continue
+		}
+
+		networkInterface, err := getNetworkInterface(ipamConfig.Interface, ipamAddresses, networkInterfaces)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get network interface for IPAMConfig %q: %w", ipamConfig.MetadataKey, err)
+		}
+		if networkInterface == nil {
+			unmatchedKeys = append(unmatchedKeys, ipamConfig.MetadataKey)
+			continue
+		}
+
+		i, ok := networkIndex[networkInterface.Name]
+		if !ok {
+			mac, err := net.ParseMAC(networkInterface.MACAddress)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid MAC address %q of network interface %q: %w", networkInterface.MACAddress, networkInterface.Name, err)
+			}
+			networks = append(networks, ignition.Network{
+				Name:       networkInterface.Name,
+				MACAddress: mac.String(),
+			})
+			i = len(networks) - 1
+			networkIndex[networkInterface.Name] = i
+		}
+
+		for _, address := range ipamAddresses {
+			ip, err := netip.ParseAddr(address.Address)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid address %q of IPAMConfig %q: %w", address.Address, ipamConfig.MetadataKey, err)
+			}
+			prefix := netip.PrefixFrom(ip, address.Prefix)
+			if !prefix.IsValid() {
+				return nil, nil, fmt.Errorf("invalid prefix %d of address %q of IPAMConfig %q", address.Prefix, address.Address, ipamConfig.MetadataKey)
+			}
+			networks[i].Addresses = append(networks[i].Addresses, prefix)
+
+			if address.Gateway != "" {
+				gateway, err := netip.ParseAddr(address.Gateway)
+				if err != nil {
+					return nil, nil, fmt.Errorf("invalid gateway %q of IPAMConfig %q: %w", address.Gateway, ipamConfig.MetadataKey, err)
+				}
+				if !slices.Contains(networks[i].Gateways, gateway) {
+					networks[i].Gateways = append(networks[i].Gateways, gateway)
+				}
+			}
+		}
+	}
+
+	return networks, unmatchedKeys, nil
+}
+
+// getNetworkInterface returns the network interface with the given name. If no name is given, the only network
+// interface of the Server is returned, or the only one with an IP address, as discovered during the inventory of the
+// Server, in the subnet of an allocated address. Nil is returned if no name is given and no interface matches.
+func getNetworkInterface(name string, addresses []capiv1beta1.IPAddressSpec, networkInterfaces []metalv1alpha1.NetworkInterface) (*metalv1alpha1.NetworkInterface, error) {
+	if name != "" {
+		for i := range networkInterfaces {
+			if networkInterfaces[i].Name == name {
+				return &networkInterfaces[i], nil
+			}
+		}
+		return nil, fmt.Errorf("server has no network interface %q", name)
+	}
+
+	if len(networkInterfaces) == 1 {
+		return &networkInterfaces[0], nil
+	}
+
+	var subnets []netip.Prefix
+	for _, address := range addresses {
+		if ip, err := netip.ParseAddr(address.Address); err == nil {
+			if subnet, err := ip.Prefix(address.Prefix); err == nil {
+				subnets = append(subnets, subnet)
+			}
+		}
+	}
+
+	var match *metalv1alpha1.NetworkInterface
+	for i := range networkInterfaces {
+		if !hasIPInSubnets(networkInterfaces[i], subnets) {
+			continue
+		}
+		if match != nil {
+			return nil, nil
+		}
+		match = &networkInterfaces[i]
+	}
+	return match, nil
+}
+
+// hasIPInSubnets returns whether one of the IP addresses of the network interface is in one of the subnets
+func hasIPInSubnets(networkInterface metalv1alpha1.NetworkInterface, subnets []netip.Prefix) bool {
+	ips := slices.Clone(networkInterface.IPs)
+	if networkInterface.IP != nil {
+		ips = append(ips, *networkInterface.IP)
+	}
+	for _, ip := range ips {
+		for _, subnet := range subnets {
+			if ip.IsValid() && subnet.Contains(ip.Addr) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metal
+
+import (
+	"net/netip"
+
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/v1alpha1"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/ignition"
+	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	capiv1beta1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
+)
+
+var _ = Describe("Network", func() {
+	networkInterfaces := []metalv1alpha1.NetworkInterface{
+		{Name: "eth0", MACAddress: "AA:BB:CC:DD:EE:00"},
+		{Name: "eth1", MACAddress: "aa:bb:cc:dd:ee:01"},
+	}
+	addresses := map[string][]capiv1beta1.IPAddressSpec{
+		"pool-a": {{Address: "10.0.0.10", Prefix: 24, Gateway: "10.0.0.1"}},
+		"pool-b": {{Address: "10.0.1.10", Prefix: 24, Gateway: "10.0.1.1"}},
+		"pool-c": {{Address: "10.0.2.10", Prefix: 24}},
+		"dual-stack": {
+			{Address: "10.0.3.10", Prefix: 24, Gateway: "10.0.3.1"},
+			{Address: "2001:db8::10", Prefix: 64, Gateway: "2001:db8::1"},
+		},
+	}
+
+	It("should configure the addresses on the network interfaces of the IPAM configs", func() {
+		Expect(getNetworks([]v1alpha1.IPAMConfig{
+			{MetadataKey: "pool-a", Interface: "eth0"},
+			{MetadataKey: "pool-b", Interface: "eth1"},
+			{MetadataKey: "pool-c", Interface: "eth0"},
+		}, addresses, networkInterfaces)).To(Equal([]ignition.Network{
+			{
+				Name:       "eth0",
+				MACAddress: "aa:bb:cc:dd:ee:00",
+				Addresses:  []netip.Prefix{netip.MustParsePrefix("10.0.0.10/24"), netip.MustParsePrefix("10.0.2.10/24")},
+				Gateways:   []netip.Addr{netip.MustParseAddr("10.0.0.1")},
+			},
+			{
+				Name:       "eth1",
+				MACAddress: "aa:bb:cc:dd:ee:01",
+				Addresses:  []netip.Prefix{netip.MustParsePrefix("10.0.1.10/24")},
+				Gateways:   []netip.Addr{netip.MustParseAddr("10.0.1.1")},
+			},
+		}))
+	})
+
+	It("should configure both addresses of a dual-stack network", func() {
+		Expect(getNetworks([]v1alpha1.IPAMConfig{{MetadataKey: "dual-stack", Interface: "eth1"}}, addresses, networkInterfaces)).To(Equal([]ignition.Network{{
+			Name:       "eth1",
+			MACAddress: "aa:bb:cc:dd:ee:01",
+			Addresses:  []netip.Prefix{netip.MustParsePrefix("10.0.3.10/24"), netip.MustParsePrefix("2001:db8::10/64")},
+			Gateways:   []netip.Addr{netip.MustParseAddr("10.0.3.1"), netip.MustParseAddr("2001:db8::1")},
+		}}))
+	})
+
+	It("should configure the only network interface if no interface is set", func() {
+		Expect(getNetworks([]v1alpha1.IPAMConfig{{MetadataKey: "pool-a"}}, addresses, networkInterfaces[:1])).To(Equal([]ignition.Network{{
+			Name:       "eth0",
+			MACAddress: "aa:bb:cc:dd:ee:00",
+			Addresses:  []netip.Prefix{netip.MustParsePrefix("10.0.0.10/24")},
+			Gateways:   []netip.Addr{netip.MustParseAddr("10.0.0.1")},
+		}}))
+	})
+
+	It("should configure the network interface in the subnet of the address if no interface is set", func() {
+		inventoriedInterfaces := []metalv1alpha1.NetworkInterface{
+			{Name: "eth0", MACAddress: "aa:bb:cc:dd:ee:00", IPs: []metalv1alpha1.IP{{Addr: netip.MustParseAddr("10.0.1.2")}}},
+			{Name: "eth1", MACAddress: "aa:bb:cc:dd:ee:01", IPs: []metalv1alpha1.IP{{Addr: netip.MustParseAddr("10.0.0.2")}}},
+		}
+		Expect(getNetworks([]v1alpha1.IPAMConfig{{MetadataKey: "pool-a"}}, addresses, inventoriedInterfaces)).To(Equal([]ignition.Network{{
+			Name:       "eth1",
+			MACAddress: "aa:bb:cc:dd:ee:01",
+			Addresses:  []netip.Prefix{netip.MustParsePrefix("10.0.0.10/24")},
+			Gateways:   []netip.Addr{netip.MustParseAddr("10.0.0.1")},
+		}}))
+
+		By("not configuring the addresses if no network interface is in their subnet")
+		networks, unmatchedKeys, err := getNetworks([]v1alpha1.IPAMConfig{{MetadataKey: "pool-a"}, {MetadataKey: "pool-c"}}, addresses, inventoriedInterfaces)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(networks).To(HaveLen(1))
+		Expect(unmatchedKeys).To(Equal([]string{"pool-c"}))
+
+		By("not configuring the addresses if the Server has multiple network interfaces without addresses")
+		networks, unmatchedKeys, err = getNetworks([]v1alpha1.IPAMConfig{{MetadataKey: "pool-a"}}, addresses, networkInterfaces)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(networks).To(BeEmpty())
+		Expect(unmatchedKeys).To(Equal([]string{"pool-a"}))
+	})
+
+	It("should fail if the network interface does not exist", func() {
+		_, _, err := getNetworks([]v1alpha1.IPAMConfig{{MetadataKey: "pool-a", Interface: "eth2"}}, addresses, networkInterfaces)
+		Expect(err).To(MatchError(`failed to get network interface for IPAMConfig "pool-a": server has no network interface "eth2"`))
+	})
+})
@@ -0,0 +1,221 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metal
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	machinev1alpha1 "github.com/gardener/machine-controller-manager/pkg/apis/machine/v1alpha1"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/validation"
+	mcmclient "github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/client"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/metrics"
+	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+	capiv1beta1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// OrphanCollectorOptions configure the collection of orphaned objects in the metal namespace
+type OrphanCollectorOptions struct {
+	// Interval is the time between two collections, a zero value disables the collector
+	Interval time.Duration
+	// GracePeriod is the time an object has to be orphaned before it is deleted
+	GracePeriod time.Duration
+	// DryRun only logs the orphaned objects instead of deleting them
+	DryRun bool
+}
+
+// OrphanCollector deletes the ServerClaims created by the driver whose Machine is gone and the ignition Secrets and
+// IPAddressClaims created by the driver whose ServerClaim is gone, e.g. because the driver crashed while deleting a
+// Machine. Objects are recognized by the LabelKeyManagedBy label, objects created by older versions of the driver
+// without the label are not collected.
+type OrphanCollector struct {
+	clientProvider *mcmclient.Provider
+	metalNamespace string
+	// controlClient reads the Machines in the controlNamespace, ServerClaims are not collected without it
+	controlClient    client.Reader
+	controlNamespace string
+	options          OrphanCollectorOptions
+
+	mu sync.Mutex
+	// orphanedSince holds the time each object was first found orphaned
+	orphanedSince map[types.UID]time.Time
+}
+
+// orphan is an object of the given kind without live ServerClaim
+type orphan struct {
+	kind   string
+	object client.Object
+}
+
+// NewOrphanCollector returns a new collector of orphaned objects in the metal namespace, the Machines are read from
+// the control namespace of the control cluster
+func NewOrphanCollector(clientProvider *mcmclient.Provider, namespace string, controlClient client.Reader, controlNamespace string, options OrphanCollectorOptions) *OrphanCollector {
+	return &OrphanCollector{
+		clientProvider:   clientProvider,
+		metalNamespace:   namespace,
+		controlClient:    controlClient,
+		controlNamespace: controlNamespace,
+		options:          options,
+		orphanedSince:    map[types.UID]time.Time{},
+	}
+}
+
+// Start runs the collection periodically until the context is done
+func (c *OrphanCollector) Start(ctx context.Context) {
+	klog.InfoS("Starting orphan collector", "namespace", c.metalNamespace, "interval", c.options.Interval, "gracePeriod", c.options.GracePeriod, "dryRun", c.options.DryRun)
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := c.collect(ctx); err != nil {
+			klog.ErrorS(err, "Failed to collect orphaned objects", "namespace", c.metalNamespace)
+		}
+	}, c.options.Interval)
+}
+
+// collect deletes the objects which have been orphaned for longer than the grace period
+func (c *OrphanCollector) collect(ctx context.Context) error {
+	serverClaimList := &metalv1alpha1.ServerClaimList{}
+	if err := c.clientProvider.SyncClient(func(metalClient client.Client) error {
+		return metalClient.List(ctx, serverClaimList, client.InNamespace(c.metalNamespace))
+	}); err != nil {
+		return fmt.Errorf("failed to list ServerClaims: %w", err)
+	}
+
+	var orphans []orphan
+
+	serverClaims := map[string]struct{}{}
+	// the ignition Secret of a ServerClaim is named after the ServerClaim, or with an ignition suffix by the old
+	// naming convention, until it is referenced by the ServerClaim
+	ignitionSecrets := map[string]struct{}{}
+	for _, serverClaim := range serverClaimList.Items {
+		serverClaims[serverClaim.Name] = struct{}{}
+		ignitionSecrets[serverClaim.Name] = struct{}{}
+		ignitionSecrets[fmt.Sprintf("%s-%s", serverClaim.Name, "ignition")] = struct{}{}
+		if serverClaim.Spec.IgnitionSecretRef != nil {
+			ignitionSecrets[serverClaim.Spec.IgnitionSecretRef.Name] = struct{}{}
+		}
+	}
+
+	serverClaimOrphans, err := c.orphanedServerClaims(ctx, serverClaimList.Items)
+	if err != nil {
+		return err
+	}
+	orphans = append(orphans, serverClaimOrphans...)
+
+	secretList := &corev1.SecretList{}
+	if err := c.clientProvider.SyncClient(func(metalClient client.Client) error {
+		return metalClient.List(ctx, secretList, client.InNamespace(c.metalNamespace), client.MatchingLabels{
+			validation.LabelKeyManagedBy: validation.LabelValueManagedBy,
+		})
+	}); err != nil {
+		return fmt.Errorf("failed to list Secrets: %w", err)
+	}
+	for i := range secretList.Items {
+		secret := &secretList.Items[i]
+		// the parts of a split ignition belong to the ignition Secret they are labelled with
+		ignitionSecretName := secret.Name
+		if name, ok := secret.Labels[validation.LabelKeyIgnitionSecretName]; ok {
+			ignitionSecretName = name
+		}
+		if _, ok := ignitionSecrets[ignitionSecretName]; !ok {
+			orphans = append(orphans, orphan{kind: "Secret", object: secret})
+		}
+	}
+
+	ipClaimList := &capiv1beta1.IPAddressClaimList{}
+	if err := c.clientProvider.SyncClient(func(metalClient client.Client) error {
+		return metalClient.List(ctx, ipClaimList, client.InNamespace(c.metalNamespace), client.HasLabels{validation.LabelKeyServerClaimName})
+	}); err != nil {
+		return fmt.Errorf("failed to list IPAddressClaims: %w", err)
+	}
+	for i := range ipClaimList.Items {
+		ipClaim := &ipClaimList.Items[i]
+		if namespace, ok := ipClaim.Labels[validation.LabelKeyServerClaimNamespace]; ok && namespace != c.metalNamespace {
+			continue
+		}
+		if _, ok := serverClaims[ipClaim.Labels[validation.LabelKeyServerClaimName]]; !ok {
+			orphans = append(orphans, orphan{kind: "IPAddressClaim", object: ipClaim})
+		}
+	}
+
+	for _, expired := range c.expiredOrphans(orphans) {
+		obj := expired.object
+		if c.options.DryRun {
+			klog.InfoS("Found orphaned object, skipping deletion in dry-run mode", "kind", expired.kind, "name", obj.GetName(), "namespace", obj.GetNamespace())
+			continue
+		}
+
+		klog.V(3).InfoS("Deleting orphaned object", "kind", expired.kind, "name", obj.GetName(), "namespace", obj.GetNamespace())
+		if err := c.clientProvider.SyncClient(func(metalClient client.Client) error {
+			return metalClient.Delete(ctx, obj, client.Preconditions{UID: ptr.To(obj.GetUID())})
+		}); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete orphaned %s %q: %w", expired.kind, client.ObjectKeyFromObject(obj), err)
+		}
+		metrics.OrphansDeleted.WithLabelValues(expired.kind).Inc()
+	}
+
+	return nil
+}
+
+// orphanedServerClaims returns the ServerClaims managed by the driver without Machine in the control namespace. The
+// Machines are listed after the ServerClaims, so that a ServerClaim created for a new Machine is not taken as orphan.
+func (c *OrphanCollector) orphanedServerClaims(ctx context.Context, serverClaims []metalv1alpha1.ServerClaim) ([]orphan, error) {
+	if c.controlClient == nil {
+		return nil, nil
+	}
+
+	machineList := &metav1.PartialObjectMetadataList{}
+	machineList.SetGroupVersionKind(machinev1alpha1.SchemeGroupVersion.WithKind("MachineList"))
+	if err := c.controlClient.List(ctx, machineList, client.InNamespace(c.controlNamespace)); err != nil {
+		return nil, fmt.Errorf("failed to list Machines: %w", err)
+	}
+	machines := make(map[string]struct{}, len(machineList.Items))
+	for _, machine := range machineList.Items {
+		machines[machine.Name] = struct{}{}
+	}
+
+	var orphans []orphan
+	for i := range serverClaims {
+		serverClaim := &serverClaims[i]
+		if serverClaim.Labels[validation.LabelKeyManagedBy] != validation.LabelValueManagedBy {
+			continue
+		}
+		if _, ok := machines[serverClaim.Name]; !ok {
+			orphans = append(orphans, orphan{kind: "ServerClaim", object: serverClaim})
+		}
+	}
+	return orphans, nil
+}
+
+// expiredOrphans remembers when the objects were first found orphaned and returns those orphaned for longer than
+// the grace period. Objects which are not orphaned anymore are forgotten.
+func (c *OrphanCollector) expiredOrphans(orphans []orphan) []orphan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	orphanedSince := make(map[types.UID]time.Time, len(orphans))
+	var expired []orphan
+	for _, o := range orphans {
+		uid := o.object.GetUID()
+		since, ok := c.orphanedSince[uid]
+		if !ok {
+			since = now
+		}
+		orphanedSince[uid] = since
+		if now.Sub(since) >= c.options.GracePeriod {
+			expired = append(expired, o)
+		}
+	}
+	c.orphanedSince = orphanedSince
+
+	return expired
+}
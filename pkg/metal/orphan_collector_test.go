@@ -0,0 +1,184 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metal
+
+import (
+	"time"
+
+	gardenermachinev1alpha1 "github.com/gardener/machine-controller-manager/pkg/apis/machine/v1alpha1"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/validation"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/cmd"
+	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/ptr"
+	capiv1beta1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
+	. "sigs.k8s.io/controller-runtime/pkg/envtest/komega"
+)
+
+var _ = Describe("OrphanCollector", func() {
+	ns, _, drv := SetupTest(cmd.NodeNamePolicyServerClaimName)
+
+	newIPAddressClaim := func(name, serverClaimName string) *capiv1beta1.IPAddressClaim {
+		return &capiv1beta1.IPAddressClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: ns.Name,
+				Name:      name,
+				Labels: map[string]string{
+					validation.LabelKeyServerClaimName:      serverClaimName,
+					validation.LabelKeyServerClaimNamespace: ns.Name,
+					validation.LabelKeyManagedBy:            validation.LabelValueManagedBy,
+				},
+			},
+			Spec: capiv1beta1.IPAddressClaimSpec{
+				PoolRef: corev1.TypedLocalObjectReference{
+					APIGroup: ptr.To("ipam.cluster.x-k8s.io"),
+					Kind:     "GlobalInClusterIPPool",
+					Name:     "pool",
+				},
+			},
+		}
+	}
+
+	managedByDriver := map[string]string{validation.LabelKeyManagedBy: validation.LabelValueManagedBy}
+
+	It("should delete the ignition Secrets and IPAddressClaims without ServerClaim", func(ctx SpecContext) {
+		By("creating a ServerClaim")
+		serverClaim := &metalv1alpha1.ServerClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: ns.Name,
+				Name:      "machine-live",
+			},
+			Spec: metalv1alpha1.ServerClaimSpec{
+				Power: metalv1alpha1.PowerOff,
+			},
+		}
+		Expect(k8sClient.Create(ctx, serverClaim)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, serverClaim)
+
+		By("creating the objects of the ServerClaim")
+		liveSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: "machine-live", Labels: managedByDriver},
+		}
+		Expect(k8sClient.Create(ctx, liveSecret)).To(Succeed())
+		livePartSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: ns.Name,
+				Name:      "machine-live-part-0",
+				Labels: map[string]string{
+					validation.LabelKeyIgnitionSecretName: "machine-live",
+					validation.LabelKeyManagedBy:          validation.LabelValueManagedBy,
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, livePartSecret)).To(Succeed())
+		liveIPClaim := newIPAddressClaim("machine-live-pxe", "machine-live")
+		Expect(k8sClient.Create(ctx, liveIPClaim)).To(Succeed())
+
+		By("creating the objects of a deleted ServerClaim")
+		orphanSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: "machine-gone", Labels: managedByDriver},
+		}
+		Expect(k8sClient.Create(ctx, orphanSecret)).To(Succeed())
+		orphanPartSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: ns.Name,
+				Name:      "machine-gone-part-0",
+				Labels: map[string]string{
+					validation.LabelKeyIgnitionSecretName: "machine-gone",
+					validation.LabelKeyManagedBy:          validation.LabelValueManagedBy,
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, orphanPartSecret)).To(Succeed())
+		orphanIPClaim := newIPAddressClaim("machine-gone-pxe", "machine-gone")
+		Expect(k8sClient.Create(ctx, orphanIPClaim)).To(Succeed())
+
+		By("creating a Secret which is not managed by the driver")
+		foreignSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: "foreign"},
+		}
+		Expect(k8sClient.Create(ctx, foreignSecret)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, foreignSecret)
+
+		clientProvider := (*drv).(*metalDriver).clientProvider
+
+		By("ensuring that nothing is deleted in dry-run mode")
+		Expect(NewOrphanCollector(clientProvider, ns.Name, nil, "", OrphanCollectorOptions{DryRun: true}).collect(ctx)).To(Succeed())
+		Consistently(Get(orphanSecret)).Should(Succeed())
+		Expect(Get(orphanPartSecret)()).To(Succeed())
+		Expect(Get(orphanIPClaim)()).To(Succeed())
+
+		By("ensuring that nothing is deleted within the grace period")
+		Expect(NewOrphanCollector(clientProvider, ns.Name, nil, "", OrphanCollectorOptions{GracePeriod: time.Hour}).collect(ctx)).To(Succeed())
+		Consistently(Get(orphanSecret)).Should(Succeed())
+		Expect(Get(orphanPartSecret)()).To(Succeed())
+		Expect(Get(orphanIPClaim)()).To(Succeed())
+
+		By("ensuring that the orphaned objects are deleted")
+		Expect(NewOrphanCollector(clientProvider, ns.Name, nil, "", OrphanCollectorOptions{}).collect(ctx)).To(Succeed())
+		Eventually(Get(orphanSecret)).Should(Satisfy(apierrors.IsNotFound))
+		Eventually(Get(orphanPartSecret)).Should(Satisfy(apierrors.IsNotFound))
+		Eventually(Get(orphanIPClaim)).Should(Satisfy(apierrors.IsNotFound))
+
+		By("ensuring that the objects of the ServerClaim and foreign objects are kept")
+		Expect(Get(liveSecret)()).To(Succeed())
+		Expect(Get(livePartSecret)()).To(Succeed())
+		Expect(Get(liveIPClaim)()).To(Succeed())
+		Expect(Get(foreignSecret)()).To(Succeed())
+	})
+
+	It("should delete the ServerClaims of the driver without Machine", func(ctx SpecContext) {
+		newServerClaim := func(name string, labels map[string]string) *metalv1alpha1.ServerClaim {
+			return &metalv1alpha1.ServerClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: ns.Name,
+					Name:      name,
+					Labels:    labels,
+				},
+				Spec: metalv1alpha1.ServerClaimSpec{
+					Power: metalv1alpha1.PowerOff,
+				},
+			}
+		}
+
+		By("creating a Machine and its ServerClaim")
+		machine := &unstructured.Unstructured{}
+		machine.SetGroupVersionKind(gardenermachinev1alpha1.SchemeGroupVersion.WithKind("Machine"))
+		machine.SetNamespace(ns.Name)
+		machine.SetName("machine-orphan-1")
+		Expect(k8sClient.Create(ctx, machine)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, machine)
+		liveServerClaim := newServerClaim(machine.GetName(), managedByDriver)
+		Expect(k8sClient.Create(ctx, liveServerClaim)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, liveServerClaim)
+
+		By("creating a ServerClaim of the driver without Machine")
+		orphanServerClaim := newServerClaim("machine-orphan-2", managedByDriver)
+		Expect(k8sClient.Create(ctx, orphanServerClaim)).To(Succeed())
+
+		By("creating a ServerClaim which is not managed by the driver")
+		foreignServerClaim := newServerClaim("foreign", nil)
+		Expect(k8sClient.Create(ctx, foreignServerClaim)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, foreignServerClaim)
+
+		clientProvider := (*drv).(*metalDriver).clientProvider
+
+		By("ensuring that ServerClaims are not collected without access to the Machines")
+		Expect(NewOrphanCollector(clientProvider, ns.Name, nil, "", OrphanCollectorOptions{}).collect(ctx)).To(Succeed())
+		Consistently(Get(orphanServerClaim)).Should(Succeed())
+
+		By("ensuring that the orphaned ServerClaim is deleted")
+		Expect(NewOrphanCollector(clientProvider, ns.Name, k8sClient, ns.Name, OrphanCollectorOptions{}).collect(ctx)).To(Succeed())
+		Eventually(Get(orphanServerClaim)).Should(Satisfy(apierrors.IsNotFound))
+
+		By("ensuring that the ServerClaim of the Machine and foreign ServerClaims are kept")
+		Expect(Get(liveServerClaim)()).To(Succeed())
+		Expect(Get(foreignServerClaim)()).To(Succeed())
+	})
+})
@@ -0,0 +1,181 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gardener/machine-controller-manager/pkg/util/provider/driver"
+	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/codes"
+	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/status"
+	apiv1alpha1 "github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/v1alpha1"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/validation"
+	mcmclient "github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/client"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/cmd"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/ignition"
+	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/record"
+	capiv1beta1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// IgnitionPreviewOptions configure the metal cluster a preview of an ignition is rendered against
+type IgnitionPreviewOptions struct {
+	// Namespace is the metal namespace
+	Namespace           string
+	NodeNamePolicy      cmd.NodeNamePolicy
+	IgnitionSizeOptions IgnitionSizeOptions
+	// Server is the Server the Machine is bound to
+	Server *metalv1alpha1.Server
+	// Addresses are the addresses allocated for the IPAM configs by their metadata key, the IPv6 address of a
+	// dual-stack network follows its IPv4 address. IPAddressClaims without address are not bound.
+	Addresses map[string][]capiv1beta1.IPAddressSpec
+	// Objects are further objects of the metal cluster, e.g. ConfigMaps and Secrets referenced by the provider spec
+	Objects []client.Object
+}
+
+// IgnitionPreview is the ignition of a Machine as it would be rendered by InitializeMachine
+type IgnitionPreview struct {
+	// Butane is the Butane config the ignition is translated from
+	Butane string
+	// Secret is the ignition Secret of the Machine
+	Secret *corev1.Secret
+	// Parts are the Secrets of the parts of an ignition which exceeds the maximum size
+	Parts []*corev1.Secret
+}
+
+// PreviewIgnition renders the ignition of a Machine along the same path as InitializeMachine, but against an in-memory
+// metal cluster. It fails with the errors InitializeMachine would return.
+func PreviewIgnition(ctx context.Context, req *driver.InitializeMachineRequest, options IgnitionPreviewOptions) (*IgnitionPreview, error) {
+	if isEmptyInitializeRequest(req) {
+		return nil, status.Error(codes.InvalidArgument, "received empty InitializeMachineRequest")
+	}
+
+	if req.MachineClass.Provider != apiv1alpha1.ProviderName {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("requested provider %q is not supported by the driver %q", req.MachineClass.Provider, apiv1alpha1.ProviderName))
+	}
+
+	providerSpec, err := GetProviderSpec(req.MachineClass, req.Secret)
+	if err != nil {
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to get provider spec: %v", err))
+	}
+
+	serverClaim, objects := newPreviewObjects(req.Machine.Name, providerSpec, options)
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(corev1.AddToScheme(scheme))
+	utilruntime.Must(metalv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(capiv1beta1.AddToScheme(scheme))
+
+	clientProvider := &mcmclient.Provider{}
+	clientProvider.SetClient(fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build())
+
+	d := &metalDriver{
+		clientProvider:      clientProvider,
+		metalNamespace:      options.Namespace,
+		nodeNamePolicy:      options.NodeNamePolicy,
+		ignitionSizeOptions: options.IgnitionSizeOptions,
+		recorder:            &record.FakeRecorder{},
+		machineRecorder:     &record.FakeRecorder{},
+		metricsTracker:      newMachineMetricsTracker(),
+	}
+
+	addressesMetaData, addresses, err := d.collectIPAddressClaimsMetadata(ctx, req, serverClaim, providerSpec)
+	if err != nil {
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to collect IPAddress metadata: %v", err))
+	}
+
+	preview, err := d.previewIgnition(ctx, req, serverClaim, providerSpec, addressesMetaData, addresses)
+	if err != nil {
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to update ignition and power on server: %v", err))
+	}
+	return preview, nil
+}
+
+// previewIgnition renders the ignition like createIgnitionAndPowerOnServer without applying it
+func (d *metalDriver) previewIgnition(ctx context.Context, req *driver.InitializeMachineRequest, serverClaim *metalv1alpha1.ServerClaim, providerSpec *apiv1alpha1.ProviderSpec, addressesMetaData map[string]any, addresses map[string][]capiv1beta1.IPAddressSpec) (*IgnitionPreview, error) {
+	nodeName, serverMetadata, networks, err := d.getServerClaimIgnitionInputs(ctx, req.Machine, serverClaim, providerSpec, addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := d.getIgnitionConfig(ctx, req, nodeName, providerSpec, addressesMetaData, addresses, serverMetadata, networks)
+	if err != nil {
+		return nil, err
+	}
+	butane, err := ignition.RenderButane(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render ignition for Machine %q: %w", client.ObjectKeyFromObject(req.Machine), err)
+	}
+
+	// the ignition Secrets are translated from the same Butane config, since rendering again would add the addresses
+	// and Server metadata to the metadata of the provider spec a second time
+	ignitionSecret, partSecrets, err := d.newIgnitionSecrets(ctx, req, providerSpec, butane, config.CompressionThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IgnitionPreview{
+		Butane: butane,
+		Secret: ignitionSecret,
+		Parts:  partSecrets,
+	}, nil
+}
+
+// newPreviewObjects returns the ServerClaim of a Machine bound to the Server of the options, and the objects of the
+// metal cluster the ignition of the Machine is rendered from
+func newPreviewObjects(machineName string, providerSpec *apiv1alpha1.ProviderSpec, options IgnitionPreviewOptions) (*metalv1alpha1.ServerClaim, []client.Object) {
+	server := &metalv1alpha1.Server{ObjectMeta: metav1.ObjectMeta{Name: "preview"}}
+	if options.Server != nil {
+		server = options.Server.DeepCopy()
+	}
+
+	serverClaim := &metalv1alpha1.ServerClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: options.Namespace,
+			Name:      machineName,
+		},
+		Spec: metalv1alpha1.ServerClaimSpec{
+			ServerRef: &corev1.LocalObjectReference{Name: server.Name},
+		},
+	}
+
+	objects := append([]client.Object{server, serverClaim}, options.Objects...)
+	for _, ipamConfig := range providerSpec.IPAMConfig {
+		for i, claimRef := range getIPAddressClaimRefs(machineName, ipamConfig) {
+			ipClaim := &capiv1beta1.IPAddressClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: options.Namespace,
+					Name:      claimRef.name,
+					Labels: map[string]string{
+						validation.LabelKeyServerClaimName:      serverClaim.Name,
+						validation.LabelKeyServerClaimNamespace: serverClaim.Namespace,
+					},
+				},
+			}
+			objects = append(objects, ipClaim)
+
+			if i >= len(options.Addresses[ipamConfig.MetadataKey]) {
+				continue
+			}
+			ipAddr := &capiv1beta1.IPAddress{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: options.Namespace,
+					Name:      claimRef.name,
+				},
+				Spec: options.Addresses[ipamConfig.MetadataKey][i],
+			}
+			ipClaim.Status.AddressRef.Name = ipAddr.Name
+			objects = append(objects, ipAddr)
+		}
+	}
+
+	return serverClaim, objects
+}
@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metal
+
+import (
+	"maps"
+
+	"github.com/gardener/machine-controller-manager/pkg/util/provider/driver"
+	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/codes"
+	"github.com/gardener/machine-controller-manager/pkg/util/provider/machinecodes/status"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/v1alpha1"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/cmd"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/ignition"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/metal/testing"
+	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capiv1beta1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("PreviewIgnition", func() {
+	ns, providerSecret, _ := SetupTest(cmd.NodeNamePolicyServerClaimName)
+	machineNamePrefix := "machine-preview"
+
+	newProviderSpec := func() map[string]any {
+		providerSpec := maps.Clone(testing.SampleProviderSpec)
+		delete(providerSpec, "metaData")
+		providerSpec["ipamConfig"] = []v1alpha1.IPAMConfig{{
+			MetadataKey: "pool-a",
+			IPAMRef: &v1alpha1.IPAMObjectReference{
+				APIGroup: "ipam.cluster.x-k8s.io",
+				Kind:     "GlobalInClusterIPPool",
+				Name:     "pool-a",
+			},
+		}}
+		return providerSpec
+	}
+
+	It("should render the ignition of a machine without a metal cluster", func(ctx SpecContext) {
+		preview, err := PreviewIgnition(ctx, &driver.InitializeMachineRequest{
+			Machine:      newMachine(ns, machineNamePrefix, 1, nil),
+			MachineClass: newMachineClass(v1alpha1.ProviderName, newProviderSpec()),
+			Secret:       providerSecret,
+		}, IgnitionPreviewOptions{
+			Namespace:      ns.Name,
+			NodeNamePolicy: cmd.NodeNamePolicyServerClaimName,
+			Server: &metalv1alpha1.Server{
+				ObjectMeta: metav1.ObjectMeta{Name: "preview-server"},
+				Spec:       metalv1alpha1.ServerSpec{SystemUUID: "12345"},
+			},
+			Addresses: map[string][]capiv1beta1.IPAddressSpec{
+				"pool-a": {{Address: "10.11.12.13", Prefix: 24, Gateway: "10.11.12.1"}},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(preview.Butane).To(ContainSubstring("machine-preview-1"))
+		Expect(preview.Secret.Namespace).To(Equal(ns.Name))
+		Expect(preview.Secret.Name).To(Equal("machine-preview-1"))
+		Expect(preview.Secret.Data).To(HaveKey(defaultIgnitionKey))
+		Expect(preview.Parts).To(BeEmpty())
+
+		By("ensuring that the ignition is translated from the Butane config")
+		Expect(ignition.TranslateButane(preview.Butane, 0)).To(MatchJSON(preview.Secret.Data[defaultIgnitionKey]))
+
+		By("ensuring that nothing has been created in the metal cluster")
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: ns.Name, Name: "machine-preview-1"}, &metalv1alpha1.ServerClaim{})).To(Satisfy(apierrors.IsNotFound))
+	})
+
+	It("should fail if an IPAddressClaim has no address", func(ctx SpecContext) {
+		_, err := PreviewIgnition(ctx, &driver.InitializeMachineRequest{
+			Machine:      newMachine(ns, machineNamePrefix, 2, nil),
+			MachineClass: newMachineClass(v1alpha1.ProviderName, newProviderSpec()),
+			Secret:       providerSecret,
+		}, IgnitionPreviewOptions{
+			Namespace:      ns.Name,
+			NodeNamePolicy: cmd.NodeNamePolicyServerClaimName,
+		})
+		Expect(err).To(MatchError(ContainSubstring("not bound")))
+	})
+
+	It("should fail if the machine request has a wrong provider", func(ctx SpecContext) {
+		_, err := PreviewIgnition(ctx, &driver.InitializeMachineRequest{
+			Machine:      newMachine(ns, machineNamePrefix, -1, nil),
+			MachineClass: newMachineClass("foo", testing.SampleProviderSpec),
+			Secret:       providerSecret,
+		}, IgnitionPreviewOptions{Namespace: ns.Name})
+		Expect(err).To(MatchError(status.Error(codes.InvalidArgument, `requested provider "foo" is not supported by the driver "ironcore-metal"`)))
+	})
+})
@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metal
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	machinev1alpha1 "github.com/gardener/machine-controller-manager/pkg/apis/machine/v1alpha1"
+	apiv1alpha1 "github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/v1alpha1"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	sigsjson "sigs.k8s.io/json"
+)
+
+var jsonPathIndex = regexp.MustCompile(`^(.*)\[(\d+)\]$`)
+
+// decodeProviderSpec decodes the provider spec of a MachineClass. Renamed fields of legacy provider specs are
+// decoded under their current name and dropped fields are ignored, both are reported as warnings. Unknown fields
+// are reported as errors, or as warnings if the MachineClass opts out of the strict decoding.
+func decodeProviderSpec(machineClass *machinev1alpha1.MachineClass, fldPath *field.Path) (*apiv1alpha1.ProviderSpec, []string, field.ErrorList, error) {
+	spec := map[string]any{}
+	if err := json.Unmarshal(machineClass.ProviderSpec.Raw, &spec); err != nil {
+		return nil, nil, nil, err
+	}
+
+	var warnings []string
+	keys := make([]string, 0, len(spec))
+	for key := range spec {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		newKey, renamed := legacyProviderSpecRenames[key]
+		switch {
+		case renamed:
+			if _, exists := spec[newKey]; exists {
+				warnings = append(warnings, fmt.Sprintf("%s is deprecated and ignored in favor of %s", fldPath.Child(key), fldPath.Child(newKey)))
+			} else {
+				spec[newKey] = spec[key]
+				warnings = append(warnings, fmt.Sprintf("%s is deprecated, use %s instead", fldPath.Child(key), fldPath.Child(newKey)))
+			}
+			delete(spec, key)
+		case slices.Contains(legacyProviderSpecDrops, key):
+			warnings = append(warnings, fmt.Sprintf("%s is deprecated and ignored", fldPath.Child(key)))
+			delete(spec, key)
+		}
+	}
+
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	providerSpec := &apiv1alpha1.ProviderSpec{}
+	if err := json.Unmarshal(data, providerSpec); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// the strict decoding is only used to detect unknown fields, the provider spec itself is decoded as before
+	strictErrs, err := sigsjson.UnmarshalStrict(data, &apiv1alpha1.ProviderSpec{}, sigsjson.DisallowUnknownFields)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var allErrs field.ErrorList
+	ignoreUnknownFields := machineClass.Annotations[validation.AnnotationKeyIgnoreUnknownFields] == "true"
+	for _, strictErr := range strictErrs {
+		fieldErr, ok := strictErr.(sigsjson.FieldError)
+		if !ok {
+			return nil, nil, nil, strictErr
+		}
+		path := getFieldPath(fldPath, fieldErr.FieldPath())
+		if ignoreUnknownFields {
+			warnings = append(warnings, fmt.Sprintf("%s is unknown and ignored", path))
+			continue
+		}
+		allErrs = append(allErrs, field.Forbidden(path, "unknown field"))
+	}
+
+	return providerSpec, warnings, allErrs, nil
+}
+
+// getFieldPath converts the path of a JSON field, e.g. ipamConfig[0].ipamRef.name, into a field path
+func getFieldPath(fldPath *field.Path, jsonPath string) *field.Path {
+	for name := range strings.SplitSeq(jsonPath, ".") {
+		var indices []int
+		for {
+			match := jsonPathIndex.FindStringSubmatch(name)
+			if match == nil {
+				break
+			}
+			index, _ := strconv.Atoi(match[2])
+			indices = append([]int{index}, indices...)
+			name = match[1]
+		}
+		fldPath = fldPath.Child(name)
+		for _, index := range indices {
+			fldPath = fldPath.Index(index)
+		}
+	}
+	return fldPath
+}
@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metal
+
+import (
+	"maps"
+
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/v1alpha1"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/validation"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/metal/testing"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var _ = Describe("ProviderSpec decoding", func() {
+	fldPath := field.NewPath("providerSpec")
+
+	It("should decode deprecated fields with warnings", func() {
+		providerSpec, warnings, allErrs, err := decodeProviderSpec(newMachineClass(v1alpha1.ProviderName, testing.SampleProviderSpec), fldPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allErrs).To(BeEmpty())
+		Expect(providerSpec.Metadata).To(Equal(map[string]any{"foo": "bar", "baz": "100"}))
+		Expect(warnings).To(Equal([]string{
+			"providerSpec.ignitionSecret is deprecated and ignored",
+			"providerSpec.machineClassName is deprecated and ignored",
+			"providerSpec.machinePoolName is deprecated and ignored",
+			"providerSpec.metaData is deprecated, use providerSpec.metadata instead",
+		}))
+	})
+
+	It("should prefer the current name over a deprecated alias", func() {
+		providerSpec, warnings, allErrs, err := decodeProviderSpec(newMachineClass(v1alpha1.ProviderName, map[string]any{
+			"image":    "my-image",
+			"metaData": map[string]any{"old": "value"},
+			"metadata": map[string]any{"new": "value"},
+		}), fldPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allErrs).To(BeEmpty())
+		Expect(providerSpec.Metadata).To(Equal(map[string]any{"new": "value"}))
+		Expect(warnings).To(ConsistOf("providerSpec.metaData is deprecated and ignored in favor of providerSpec.metadata"))
+	})
+
+	It("should reject unknown fields with their paths", func() {
+		spec := maps.Clone(testing.SampleProviderSpec)
+		spec["serverLabel"] = map[string]string{"instance-type": "bar"}
+		spec["ipamConfig"] = []map[string]any{{
+			"metadataKey": "pxe",
+			"ipamRef":     map[string]any{"apiGroup": "ipam.cluster.x-k8s.io", "kind": "GlobalInClusterIPPool", "nmae": "pool"},
+		}}
+
+		_, _, allErrs, err := decodeProviderSpec(newMachineClass(v1alpha1.ProviderName, spec), fldPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allErrs).To(ConsistOf(
+			field.Forbidden(field.NewPath("providerSpec", "serverLabel"), "unknown field"),
+			field.Forbidden(field.NewPath("providerSpec", "ipamConfig").Index(0).Child("ipamRef", "nmae"), "unknown field"),
+		))
+
+		By("failing to get the provider spec")
+		_, err = GetProviderSpec(newMachineClass(v1alpha1.ProviderName, spec), &corev1.Secret{Data: map[string][]byte{"userData": []byte("abcd")}})
+		Expect(err).To(MatchError(ContainSubstring("providerSpec.serverLabel: Forbidden: unknown field")))
+	})
+
+	It("should only warn about unknown fields if the MachineClass opts out of the strict decoding", func() {
+		spec := maps.Clone(testing.SampleProviderSpec)
+		spec["dnsServer"] = []string{"1.2.3.4"}
+		machineClass := newMachineClass(v1alpha1.ProviderName, spec)
+		machineClass.Annotations = map[string]string{validation.AnnotationKeyIgnoreUnknownFields: "true"}
+
+		_, warnings, allErrs, err := decodeProviderSpec(machineClass, fldPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allErrs).To(BeEmpty())
+		Expect(warnings).To(ContainElement("providerSpec.dnsServer is unknown and ignored"))
+	})
+})
@@ -19,6 +19,7 @@ import (
 	gardenermachinev1alpha1 "github.com/gardener/machine-controller-manager/pkg/apis/machine/v1alpha1"
 	"github.com/ironcore-dev/controller-utils/modutils"
 	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/v1alpha1"
+	"github.com/ironcore-dev/machine-controller-manager-provider-ironcore-metal/pkg/api/validation"
 	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -27,6 +28,7 @@ import (
 	kuberuntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	capiv1beta1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
@@ -45,6 +47,9 @@ var (
 	testEnv   *envtest.Environment
 	cfg       *rest.Config
 	k8sClient client.Client
+	recorder  *record.FakeRecorder
+	// machineRecorder records the events emitted on Machines
+	machineRecorder *record.FakeRecorder
 )
 
 func TestAPIs(t *testing.T) {
@@ -144,12 +149,31 @@ func SetupTest(nodeNamePolicy cmd.NodeNamePolicy) (*corev1.Namespace, *corev1.Se
 		clientProvider := &mcmclient.Provider{}
 		clientProvider.SetClient(userClient)
 
-		drv = NewDriver(clientProvider, ns.Name, nodeNamePolicy)
+		recorder = record.NewFakeRecorder(1024)
+		machineRecorder = record.NewFakeRecorder(1024)
+		drv = NewDriver(clientProvider, ns.Name, DriverOptions{
+			NodeNamePolicy:  nodeNamePolicy,
+			Recorder:        recorder,
+			MachineRecorder: machineRecorder,
+		})
 	})
 
 	return ns, secret, &drv
 }
 
+// deleteMachine deletes the machine and waits until its deletion has finished. Since envtest does not run the
+// garbage collector, the IPAddressClaims owned by the ServerClaim are removed explicitly.
+func deleteMachine(ctx SpecContext, drv driver.Driver, req *driver.DeleteMachineRequest) {
+	Expect(k8sClient.DeleteAllOf(ctx, &capiv1beta1.IPAddressClaim{}, client.InNamespace(req.Machine.Namespace), client.MatchingLabels{
+		validation.LabelKeyServerClaimName: req.Machine.Name,
+	})).To(Succeed())
+
+	Eventually(func() error {
+		_, err := drv.DeleteMachine(ctx, req)
+		return err
+	}).Should(Succeed())
+}
+
 func newMachine(namespace *corev1.Namespace, prefix string, setMachineIndex int, annotations map[string]string) *gardenermachinev1alpha1.Machine {
 	index := max(setMachineIndex, 0)
 
@@ -160,7 +160,7 @@ WantedBy=multi-user.target
 					"path": "/var/lib/metal-cloud-config/metadata",
 					"contents": map[string]any{
 						"compression": "",
-						"source":      "data:;base64,eyJiYXoiOiIxMDAiLCJmb28iOiJiYXIiLCJsb29wYmFja0FkZHJlc3MiOiIyMDAxOmRiODo6MSJ9",
+						"source":      "data:;base64,eyJiYXoiOiIxMDAiLCJmb28iOiJiYXIiLCJsb29wYmFja0FkZHJlc3MiOiIyMDAxOmRiODo6MSIsImxvb3BiYWNrQWRkcmVzc2VzIjpbIjIwMDE6ZGI4OjoxIl19",
 					},
 					"mode": 420.0,
 				},
@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: 2024 SAP SE or an SAP affiliate company and IronCore contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "mcm"
+	subsystem = "metal"
+
+	// LabelMachineClass is the label for the name of the MachineClass
+	LabelMachineClass = "machine_class"
+	// LabelServerSelector is the label for the server selector of the MachineClass
+	LabelServerSelector = "server_selector"
+	// LabelPool is the label for the IPAM pool an IP address is allocated from
+	LabelPool = "pool"
+	// LabelKind is the label for the kind of an object
+	LabelKind = "kind"
+)
+
+var (
+	// ServerClaimBindingDuration records the time from the creation of a ServerClaim until it is bound to a Server
+	ServerClaimBindingDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "server_claim_binding_duration_seconds",
+		Help:      "Time(in seconds) from the creation of a ServerClaim until it is bound to a Server.",
+		Buckets:   prometheus.ExponentialBuckets(5, 2, 12),
+	}, []string{LabelMachineClass, LabelServerSelector})
+
+	// IgnitionRenderDuration records the time it takes to render the ignition of a Machine
+	IgnitionRenderDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "ignition_render_duration_seconds",
+		Help:      "Time(in seconds) it takes to render the ignition of a Machine.",
+	}, []string{LabelMachineClass, LabelServerSelector})
+
+	// IgnitionRenderFailures counts the failed ignition renderings
+	IgnitionRenderFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "ignition_render_failures_total",
+		Help:      "Number of failed ignition renderings.",
+	}, []string{LabelMachineClass, LabelServerSelector})
+
+	// IPAddressClaimAllocationDuration records the time from the creation of an IPAddressClaim until it is bound to an IPAddress
+	IPAddressClaimAllocationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "ip_address_claim_allocation_duration_seconds",
+		Help:      "Time(in seconds) from the creation of an IPAddressClaim until it is bound to an IPAddress, partitioned by pool.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+	}, []string{LabelMachineClass, LabelServerSelector, LabelPool})
+
+	// DeleteMachineWaitDuration records the time from the first deletion request of a Machine until all its resources are gone
+	DeleteMachineWaitDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "delete_machine_wait_duration_seconds",
+		Help:      "Time(in seconds) from the first deletion request of a Machine until its ServerClaim and IPAddressClaims are gone.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+	}, []string{LabelMachineClass, LabelServerSelector})
+
+	// ServerClaimRecreateMarked counts the ServerClaims which were marked for recreation since no Server was bound
+	ServerClaimRecreateMarked = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "server_claim_recreate_marked_total",
+		Help:      "Number of ServerClaims marked for recreation because no Server was bound.",
+	}, []string{LabelMachineClass, LabelServerSelector})
+
+	// OrphansDeleted counts the orphaned objects deleted by the orphan collector
+	OrphansDeleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "orphans_deleted_total",
+		Help:      "Number of orphaned objects deleted from the metal namespace, partitioned by kind.",
+	}, []string{LabelKind})
+)
+
+func init() {
+	prometheus.MustRegister(
+		ServerClaimBindingDuration,
+		IgnitionRenderDuration,
+		IgnitionRenderFailures,
+		IPAddressClaimAllocationDuration,
+		DeleteMachineWaitDuration,
+		ServerClaimRecreateMarked,
+		OrphansDeleted,
+	)
+}